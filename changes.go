@@ -0,0 +1,280 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// A ChangeReport lists the differences between two registry snapshots.
+type ChangeReport struct {
+	AddedCommands   []string
+	RemovedCommands []string
+	ChangedCommands []string // Commands whose C signature changed
+
+	AddedEnums   []string
+	RemovedEnums []string
+	ChangedEnums []string // Enums whose value changed
+
+	AddedExtensions   []string
+	RemovedExtensions []string
+
+	AddedRequirements   []string // Feature requirements, e.g., "gl 3.3 core: require glFoo"
+	RemovedRequirements []string
+}
+
+// registrySymbols is the flattened, comparable view of one registry snapshot.
+type registrySymbols struct {
+	commands     map[string]string // Command name to C signature
+	enums        map[string]string // Enum name to value
+	extensions   map[string]bool
+	requirements map[string]bool
+}
+
+func changes(name string, args []string) {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	glowBaseDir := determineGlowBaseDir()
+	var (
+		oldDir  = flags.String("old", "", "XML directory of the previous registry snapshot")
+		newDir  = flags.String("new", filepath.Join(glowBaseDir, "xml"), "XML directory of the updated registry snapshot")
+		api     = flags.String("api", "", "If non-empty, only report changes relevant to this API (e.g., gl)")
+		ver     = flags.String("version", "all", "API version to report changes for (e.g., 4.1); requires api")
+		profile = flags.String("profile", "", "API profile to report changes for (e.g., core); requires api")
+		addext  = flags.String("addext", "", "If non-empty, a regular expression describing which extensions to include in addition to those supported by the selected profile; takes precedence over explicit removal")
+		remext  = flags.String("remext", "", "If non-empty, a regular expression describing which extensions to exclude")
+	)
+	flags.Parse(args)
+
+	if *oldDir == "" {
+		log.Fatalln("missing previous registry snapshot; use -old")
+	}
+
+	var packageSpec *PackageSpec
+	if *api != "" {
+		version, err := ParseVersion(*ver)
+		if err != nil {
+			log.Fatalln("error parsing version:", err)
+		}
+		packageSpec = &PackageSpec{
+			API:     *api,
+			Version: version,
+			Profile: *profile,
+		}
+		if *addext != "" {
+			if packageSpec.AddExtRegexp, err = regexp.Compile(*addext); err != nil {
+				log.Fatalln("error parsing extension inclusion regexp:", err)
+			}
+		}
+		if *remext != "" {
+			if packageSpec.RemExtRegexp, err = regexp.Compile(*remext); err != nil {
+				log.Fatalln("error parsing extension exclusion regexp:", err)
+			}
+		}
+	}
+
+	oldSpecs := parseSpecifications(*oldDir)
+	newSpecs := parseSpecifications(*newDir)
+
+	report := NewChangeReport(oldSpecs, newSpecs, packageSpec)
+	report.WriteTo(os.Stdout)
+}
+
+// NewChangeReport compares two sets of specifications. If pkgSpec is non-nil
+// only the commands, enums, extensions and feature requirements that would
+// contribute to the described package are compared.
+func NewChangeReport(oldSpecs, newSpecs []*Specification, pkgSpec *PackageSpec) *ChangeReport {
+	oldSymbols := collectSymbols(oldSpecs, pkgSpec)
+	newSymbols := collectSymbols(newSpecs, pkgSpec)
+
+	report := &ChangeReport{}
+	report.AddedCommands, report.RemovedCommands, report.ChangedCommands =
+		diffValues(oldSymbols.commands, newSymbols.commands)
+	report.AddedEnums, report.RemovedEnums, report.ChangedEnums =
+		diffValues(oldSymbols.enums, newSymbols.enums)
+	report.AddedExtensions, report.RemovedExtensions =
+		diffSets(oldSymbols.extensions, newSymbols.extensions)
+	report.AddedRequirements, report.RemovedRequirements =
+		diffSets(oldSymbols.requirements, newSymbols.requirements)
+	return report
+}
+
+// IsEmpty returns true if the report lists no changes.
+func (r *ChangeReport) IsEmpty() bool {
+	return len(r.AddedCommands)+len(r.RemovedCommands)+len(r.ChangedCommands)+
+		len(r.AddedEnums)+len(r.RemovedEnums)+len(r.ChangedEnums)+
+		len(r.AddedExtensions)+len(r.RemovedExtensions)+
+		len(r.AddedRequirements)+len(r.RemovedRequirements) == 0
+}
+
+// WriteTo writes a human-readable version of the report.
+func (r *ChangeReport) WriteTo(w io.Writer) (int64, error) {
+	if r.IsEmpty() {
+		n, err := fmt.Fprintln(w, "No changes.")
+		return int64(n), err
+	}
+
+	sections := []struct {
+		title   string
+		entries []string
+	}{
+		{"Added commands", r.AddedCommands},
+		{"Removed commands", r.RemovedCommands},
+		{"Changed commands", r.ChangedCommands},
+		{"Added enums", r.AddedEnums},
+		{"Removed enums", r.RemovedEnums},
+		{"Changed enums", r.ChangedEnums},
+		{"Added extensions", r.AddedExtensions},
+		{"Removed extensions", r.RemovedExtensions},
+		{"Added feature requirements", r.AddedRequirements},
+		{"Removed feature requirements", r.RemovedRequirements},
+	}
+
+	var total int64
+	for _, section := range sections {
+		if len(section.entries) == 0 {
+			continue
+		}
+		n, err := fmt.Fprintf(w, "%s (%d):\n", section.title, len(section.entries))
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+		for _, entry := range section.entries {
+			n, err := fmt.Fprintf(w, "  %s\n", entry)
+			total += int64(n)
+			if err != nil {
+				return total, err
+			}
+		}
+	}
+	return total, nil
+}
+
+func collectSymbols(specs []*Specification, pkgSpec *PackageSpec) registrySymbols {
+	symbols := registrySymbols{
+		commands:     make(map[string]string),
+		enums:        make(map[string]string),
+		extensions:   make(map[string]bool),
+		requirements: make(map[string]bool),
+	}
+
+	for _, spec := range specs {
+		if pkgSpec != nil {
+			if !spec.HasPackage(pkgSpec) {
+				continue
+			}
+			pkg := spec.ToPackage(pkgSpec)
+			for name, fn := range pkg.Functions {
+				symbols.commands[name] = cSignature(&fn.Function)
+			}
+			for name, enum := range pkg.Enums {
+				symbols.enums[name] = enum.Value
+			}
+		} else {
+			for ref, fn := range spec.Functions {
+				// Prefer the API-agnostic definition when there are several
+				if _, ok := symbols.commands[ref.name]; !ok || ref.api == "" {
+					symbols.commands[ref.name] = cSignature(fn)
+				}
+			}
+			for ref, enum := range spec.Enums {
+				if _, ok := symbols.enums[ref.name]; !ok || ref.api == "" {
+					symbols.enums[ref.name] = enum.Value
+				}
+			}
+		}
+
+		for _, feature := range spec.Features {
+			if pkgSpec != nil && !feature.shouldInclude(pkgSpec) {
+				continue
+			}
+			for _, addRem := range feature.AddRem {
+				if pkgSpec != nil && !addRem.shouldInclude(pkgSpec) {
+					continue
+				}
+				scope := feature.API + " " + feature.Version.String()
+				if addRem.profile != "" {
+					scope += " " + addRem.profile
+				}
+				addRequirements(symbols.requirements, scope, "require", addRem.addedCommands)
+				addRequirements(symbols.requirements, scope, "require", addRem.addedEnums)
+				addRequirements(symbols.requirements, scope, "remove", addRem.removedCommands)
+				addRequirements(symbols.requirements, scope, "remove", addRem.removedEnums)
+			}
+		}
+
+		for _, extension := range spec.Extensions {
+			if pkgSpec != nil && !extension.shouldInclude(pkgSpec) {
+				continue
+			}
+			symbols.extensions[extension.Name] = true
+		}
+	}
+
+	return symbols
+}
+
+func addRequirements(requirements map[string]bool, scope, verb string, names []string) {
+	for _, name := range names {
+		requirements[fmt.Sprintf("%s: %s %s", scope, verb, name)] = true
+	}
+}
+
+// cSignature returns a normalized C declaration of the function.
+func cSignature(fn *Function) string {
+	params := make([]string, 0, len(fn.Parameters))
+	for _, p := range fn.Parameters {
+		params = append(params, cDeclaration(p.Type, p.Name))
+	}
+	return cDeclaration(fn.Return, fn.Name) + "(" + strings.Join(params, ", ") + ")"
+}
+
+func cDeclaration(t Type, name string) string {
+	ctype := strings.Join(strings.Fields(t.CDefinition), " ")
+	if strings.HasSuffix(ctype, "*") {
+		return ctype + name
+	}
+	return ctype + " " + name
+}
+
+func diffValues(oldValues, newValues map[string]string) (added, removed, changed []string) {
+	for name, newValue := range newValues {
+		oldValue, ok := oldValues[name]
+		if !ok {
+			added = append(added, name)
+		} else if oldValue != newValue {
+			changed = append(changed, fmt.Sprintf("%s: %s -> %s", name, oldValue, newValue))
+		}
+	}
+	for name := range oldValues {
+		if _, ok := newValues[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
+
+func diffSets(oldSet, newSet map[string]bool) (added, removed []string) {
+	for name := range newSet {
+		if !oldSet[name] {
+			added = append(added, name)
+		}
+	}
+	for name := range oldSet {
+		if !newSet[name] {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+const changesOldRegistry = `<registry>
+  <enums>
+    <enum name="GL_ONE" value="1"/>
+    <enum name="GL_TWO" value="2"/>
+    <enum name="GL_THREE" value="3"/>
+  </enums>
+  <commands>
+    <command><proto>void <name>glKeep</name></proto></command>
+    <command><proto>void <name>glDrop</name></proto></command>
+    <command>
+      <proto>void <name>glChange</name></proto>
+      <param><ptype>GLint</ptype> <name>x</name></param>
+    </command>
+  </commands>
+  <feature api="gl" name="GL_VERSION_1_0" number="1.0">
+    <require>
+      <command name="glKeep"/>
+      <command name="glDrop"/>
+      <command name="glChange"/>
+      <enum name="GL_ONE"/>
+      <enum name="GL_TWO"/>
+    </require>
+  </feature>
+  <extensions>
+    <extension name="GL_EXT_old" supported="gl">
+      <require><enum name="GL_THREE"/></require>
+    </extension>
+  </extensions>
+</registry>`
+
+const changesNewRegistry = `<registry>
+  <enums>
+    <enum name="GL_ONE" value="1"/>
+    <enum name="GL_TWO" value="0x2"/>
+    <enum name="GL_FOUR" value="4"/>
+    <enum name="GL_ES_ONLY" value="5"/>
+  </enums>
+  <commands>
+    <command><proto>void <name>glKeep</name></proto></command>
+    <command><proto>void <name>glAdd</name></proto></command>
+    <command>
+      <proto>void <name>glChange</name></proto>
+      <param><ptype>GLint64</ptype> <name>x</name></param>
+    </command>
+  </commands>
+  <feature api="gl" name="GL_VERSION_1_0" number="1.0">
+    <require>
+      <command name="glKeep"/>
+      <command name="glAdd"/>
+      <command name="glChange"/>
+      <enum name="GL_ONE"/>
+      <enum name="GL_TWO"/>
+    </require>
+  </feature>
+  <feature api="gles2" name="GL_ES_VERSION_2_0" number="2.0">
+    <require><enum name="GL_ES_ONLY"/></require>
+  </feature>
+  <extensions>
+    <extension name="GL_EXT_new" supported="gl">
+      <require><enum name="GL_FOUR"/></require>
+    </extension>
+  </extensions>
+</registry>`
+
+func mustParseSpecification(t *testing.T, registryXML string) *Specification {
+	t.Helper()
+	var registry xmlRegistry
+	if err := xml.Unmarshal([]byte(registryXML), &registry); err != nil {
+		t.Fatalf("failed to unmarshal registry: %v", err)
+	}
+	spec, err := NewSpecification(registry, xmlOverloads{})
+	if err != nil {
+		t.Fatalf("failed to parse registry: %v", err)
+	}
+	return spec
+}
+
+func TestChangeReport(t *testing.T) {
+	oldSpec := mustParseSpecification(t, changesOldRegistry)
+	newSpec := mustParseSpecification(t, changesNewRegistry)
+
+	report := NewChangeReport([]*Specification{oldSpec}, []*Specification{newSpec}, nil)
+	expected := &ChangeReport{
+		AddedCommands:     []string{"glAdd"},
+		RemovedCommands:   []string{"glDrop"},
+		ChangedCommands:   []string{"glChange: void glChange(GLint x) -> void glChange(GLint64 x)"},
+		AddedEnums:        []string{"GL_ES_ONLY", "GL_FOUR"},
+		RemovedEnums:      []string{"GL_THREE"},
+		ChangedEnums:      []string{"GL_TWO: 2 -> 0x2"},
+		AddedExtensions:   []string{"GL_EXT_new"},
+		RemovedExtensions: []string{"GL_EXT_old"},
+		AddedRequirements: []string{
+			"gl 1.0: require glAdd",
+			"gles2 2.0: require GL_ES_ONLY",
+		},
+		RemovedRequirements: []string{"gl 1.0: require glDrop"},
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("unexpected report\n got: %+v\nwant: %+v", report, expected)
+	}
+}
+
+func TestChangeReportFiltered(t *testing.T) {
+	oldSpec := mustParseSpecification(t, changesOldRegistry)
+	newSpec := mustParseSpecification(t, changesNewRegistry)
+
+	pkgSpec := &PackageSpec{API: "gl", Version: Version{1, 0}}
+	report := NewChangeReport([]*Specification{oldSpec}, []*Specification{newSpec}, pkgSpec)
+
+	// GL_ES_ONLY is only part of the gles2 API and must not be reported
+	expectedAddedEnums := []string{"GL_FOUR"}
+	if !reflect.DeepEqual(report.AddedEnums, expectedAddedEnums) {
+		t.Errorf("added enums: got %v, want %v", report.AddedEnums, expectedAddedEnums)
+	}
+	expectedAddedRequirements := []string{"gl 1.0: require glAdd"}
+	if !reflect.DeepEqual(report.AddedRequirements, expectedAddedRequirements) {
+		t.Errorf("added requirements: got %v, want %v", report.AddedRequirements, expectedAddedRequirements)
+	}
+	if !reflect.DeepEqual(report.RemovedEnums, []string{"GL_THREE"}) {
+		t.Errorf("removed enums: got %v, want [GL_THREE]", report.RemovedEnums)
+	}
+}
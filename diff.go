@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// maxEditDistance bounds the work done by diffLines. Inputs that differ by
+// more lines are reported as a complete replacement.
+const maxEditDistance = 4000
+
+type diffOp struct {
+	kind byte // One of ' ', '-' or '+'
+	text string
+}
+
+// unifiedDiff returns the differences between two texts in unified diff
+// format, or an empty string if they are equal.
+func unifiedDiff(oldName, newName string, oldData, newData []byte) string {
+	if string(oldData) == string(newData) {
+		return ""
+	}
+	ops := diffLines(splitLines(string(oldData)), splitLines(string(newData)))
+
+	// Determine the ranges of operations to show, merging close changes
+	type hunkRange struct{ start, end int }
+	var ranges []hunkRange
+	for i, op := range ops {
+		if op.kind == ' ' {
+			continue
+		}
+		start, end := i-diffContext, i+diffContext+1
+		if start < 0 {
+			start = 0
+		}
+		if end > len(ops) {
+			end = len(ops)
+		}
+		if n := len(ranges); n > 0 && start <= ranges[n-1].end {
+			ranges[n-1].end = end
+		} else {
+			ranges = append(ranges, hunkRange{start, end})
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+	oldLine, newLine, next := 0, 0, 0
+	for _, r := range ranges {
+		for ; next < r.start; next++ {
+			oldLine, newLine = advanceLines(ops[next], oldLine, newLine)
+		}
+		oldCount, newCount := 0, 0
+		for _, op := range ops[r.start:r.end] {
+			oldCount, newCount = advanceLines(op, oldCount, newCount)
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkPosition(oldLine, oldCount), hunkPosition(newLine, newCount))
+		for _, op := range ops[r.start:r.end] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.text)
+			out.WriteByte('\n')
+		}
+	}
+	return out.String()
+}
+
+func advanceLines(op diffOp, oldLine, newLine int) (int, int) {
+	if op.kind != '+' {
+		oldLine++
+	}
+	if op.kind != '-' {
+		newLine++
+	}
+	return oldLine, newLine
+}
+
+func hunkPosition(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines computes a minimal line edit script using Myers' algorithm.
+func diffLines(a, b []string) []diffOp {
+	// Common prefixes and suffixes are cheap to strip and typically make up
+	// most of a regenerated file.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+func myersDiff(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+
+	// trace[d] holds the furthest reaching x for diagonals -d-1...d+1 before
+	// step d, which is all that is needed to backtrack.
+	var trace [][]int
+	for d := 0; d <= max && d <= maxEditDistance; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return myersBacktrack(a, b, trace)
+			}
+		}
+	}
+
+	// Too many differences, replace everything
+	ops := make([]diffOp, 0, n+m)
+	for _, line := range a {
+		ops = append(ops, diffOp{'-', line})
+	}
+	for _, line := range b {
+		ops = append(ops, diffOp{'+', line})
+	}
+	return ops
+}
+
+func myersBacktrack(a, b []string, trace [][]int) []diffOp {
+	var reversed []diffOp
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, diffOp{' ', a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, diffOp{'+', b[y-1]})
+			} else {
+				reversed = append(reversed, diffOp{'-', a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	ops := make([]diffOp, len(reversed))
+	for i, op := range reversed {
+		ops[len(reversed)-1-i] = op
+	}
+	return ops
+}
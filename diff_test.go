@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tt := []struct {
+		name     string
+		old, new string
+		expected string
+	}{
+		{
+			name:     "equal",
+			old:      "a\nb\n",
+			new:      "a\nb\n",
+			expected: "",
+		},
+		{
+			name: "change",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			new:  "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			expected: `--- old
++++ new
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+`,
+		},
+		{
+			name: "separate hunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			new:  "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			expected: `--- old
++++ new
+@@ -1,3 +1,4 @@
++0
+ 1
+ 2
+ 3
+@@ -7,4 +8,3 @@
+ 7
+ 8
+ 9
+-10
+`,
+		},
+		{
+			name: "from empty",
+			old:  "",
+			new:  "a\n",
+			expected: `--- old
++++ new
+@@ -0,0 +1,1 @@
++a
+`,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			diff := unifiedDiff("old", "new", []byte(tc.old), []byte(tc.new))
+			if diff != tc.expected {
+				t.Errorf("unexpected diff\n got:\n%s\nwant:\n%s", diff, tc.expected)
+			}
+		})
+	}
+}
+
+func TestDiffLinesMinimal(t *testing.T) {
+	a := strings.Split("a b c a b b a", " ")
+	b := strings.Split("c b a b a c", " ")
+	edits := 0
+	for _, op := range diffLines(a, b) {
+		if op.kind != ' ' {
+			edits++
+		}
+	}
+	if edits != 5 {
+		t.Errorf("expected 5 edits, got %d", edits)
+	}
+}
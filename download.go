@@ -52,6 +52,12 @@ var specRegexp = regexp.MustCompile(`^(gl|glx|wgl)\.xml$`)
 var eglRepoName = "EGL-Registry"
 var eglRepoFolder = "api"
 var eglRegexp = regexp.MustCompile(`^(egl)\.xml$`)
+var clRepoName = "OpenCL-Docs"
+var clRepoFolder = "xml"
+var clRegexp = regexp.MustCompile(`^(cl)\.xml$`)
+var vkRepoName = "Vulkan-Docs"
+var vkRepoFolder = "xml"
+var vkRegexp = regexp.MustCompile(`^(vk)\.xml$`)
 var khrRepoName = "EGL-Registry"
 var khrRepoFolder = "api/KHR"
 var khrRegexp = regexp.MustCompile(`^.*\.h$`)
@@ -132,6 +138,16 @@ func download(name string, args []string) {
 		log.Fatalln("error downloading egl file:", err)
 	}
 
+	err = DownloadGitDir(authHeader, clRepoName, clRepoFolder, clRegexp, specDir)
+	if err != nil {
+		log.Fatalln("error downloading opencl file:", err)
+	}
+
+	err = DownloadGitDir(authHeader, vkRepoName, vkRepoFolder, vkRegexp, specDir)
+	if err != nil {
+		log.Fatalln("error downloading vulkan file:", err)
+	}
+
 	err = DownloadGitDir(authHeader, khrRepoName, khrRepoFolder, khrRegexp, khrDir)
 	if err != nil {
 		log.Fatalln("error downloading include KHR files:", err)
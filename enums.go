@@ -1,8 +1,32 @@
 package main
 
+import "fmt"
+
 // An Enum represents an enumerated value.
 type Enum struct {
 	Name   string // Raw specification name
 	GoName string // Go name with the API prefix stripped
 	Value  string // Raw specification value
+	Alias  string // Name of the aliased enum, if the value is left to it
+
+	Version         Version  // Feature version that introduced the enum to a package, if any
+	Extension       string   // Extension that introduced the enum to a package, if any
+	OtherExtensions []string // Other extensions adding the enum to a package, if any
+	Removed         *Removal // Removal of the enum by a profile the package does not target, if any
+}
+
+// A Removal describes the removal of an enum or function by a feature version
+// of a profile, e.g., of glBegin by OpenGL 3.2 core.
+type Removal struct {
+	Version Version // Feature version removing the symbol
+	Profile string  // Profile removing the symbol, or empty if all do
+}
+
+// Deprecation returns the godoc deprecation notice of the removal, e.g.,
+// "Deprecated: removed from core profile in 3.2.".
+func (r *Removal) Deprecation() string {
+	if r.Profile == "" {
+		return fmt.Sprintf("Deprecated: removed in %s.", r.Version)
+	}
+	return fmt.Sprintf("Deprecated: removed from %s profile in %s.", r.Profile, r.Version)
 }
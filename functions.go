@@ -9,6 +9,7 @@ type Function struct {
 	Parameters []Parameter
 	Return     Type
 	Overloads  []Overload
+	Aliases    []string // C names of the equivalent commands aliasing the function, e.g., glBindBufferARB
 }
 
 // An Overload describes an alternative signature for the same function.
@@ -35,6 +36,13 @@ func (p Parameter) GoName() string {
 	return renameIfReservedGoWord(p.Name)
 }
 
+// IsUserParam returns whether the parameter passes user data through to a
+// callback, e.g., userParam of glDebugMessageCallback or user_data of
+// clCreateContext.
+func (p Parameter) IsUserParam() bool {
+	return (p.Name == "userParam" || p.Name == "user_data") && p.Type.Name == "void" && p.Type.PointerLevel == 1
+}
+
 func renameIfReservedCWord(word string) string {
 	switch word {
 	case "near", "far":
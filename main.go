@@ -21,20 +21,91 @@ func generate(name string, args []string) {
 		xmlDir      = flags.String("xml", filepath.Join(glowBaseDir, "xml"), "XML directory")
 		tmplDir     = flags.String("tmpl", filepath.Join(glowBaseDir, "tmpl"), "Template directory")
 		outDir      = flags.String("out", "gl", "Output directory")
-		api         = flags.String("api", "", "API to generate (e.g., gl)")
-		ver         = flags.String("version", "", "API version to generate (e.g., 4.1)")
-		profile     = flags.String("profile", "", "API profile to generate (e.g., core)")
+		api         = flags.String("api", "", "API to generate (e.g., gl), or a comma-separated list of APIs to combine into one package (e.g., gl,gles2)")
+		ver         = flags.String("version", "", "API version to generate (e.g., 4.1), or a range of versions whose later ones Init loads leniently (e.g., 3.3-4.6); one per API if combined")
+		profile     = flags.String("profile", "", "API profile to generate (e.g., core); one per API if combined")
+		combine     = flags.String("combine", "union", "Symbols of a combined package: union, or intersection for those common to all its APIs")
+		backend     = flags.String("backend", "cgo", "Implementation of the functions: cgo, or webgl for WebGL 2 through syscall/js (gles2 only)")
 		addext      = flags.String("addext", "", "If non-empty, a regular expression describing which extensions to include in addition to those supported by the selected profile; takes precedence over explicit removal")
 		remext      = flags.String("remext", "", "If non-empty, a regular expression describing which extensions to exclude")
 		restrict    = flags.String("restrict", "", "JSON file of symbols to restrict symbol generation")
 		lenientInit = flags.Bool("lenientInit", false, "When true missing functions do not fail Init")
+		split       = flags.Bool("split", false, "When true generate one file per feature version and extension")
+		extTags     = flags.Bool("extTags", false, "When true guard each extension with a glow_no_<extension> build tag; implies -split")
+		strictVer   = flags.Bool("strictVersion", false, "When true Init fails if the context is older than the generated version or its profile differs")
+		lazyInit    = flags.Bool("lazyInit", false, "When true functions are loaded on first use instead of by Init")
+		aliasFall   = flags.Bool("aliasFallback", false, "When true Init binds the ARB/EXT/OES alias of a function missing from the context")
+		headless    = flags.Bool("headless", false, "When true include NewHeadlessContext, which creates a context without a window system (egl only)")
+		check       = flags.Bool("check", false, "When true compare the output directory against the generated package instead of writing it, exiting non-zero if they differ")
 	)
 	flags.Parse(args)
 
-	version, err := ParseVersion(*ver)
+	// Combined packages list a version and a profile per API
+	apis := strings.Split(*api, ",")
+	vers := strings.Split(*ver, ",")
+	profiles := strings.Split(*profile, ",")
+	if *profile == "" {
+		profiles = make([]string, len(apis))
+	}
+	if len(vers) != len(apis) || len(profiles) != len(apis) {
+		log.Fatalln("combined packages require a version and a profile per API")
+	}
+	if len(apis) > 1 {
+		seen := make(map[string]bool)
+		for _, a := range apis {
+			if !isCombinableAPI(a) || seen[a] {
+				log.Fatalln("combined packages require distinct gl, gles1, gles2, or glsc2 APIs")
+			}
+			seen[a] = true
+		}
+		if *combine != "union" && *combine != "intersection" {
+			log.Fatalln("unknown combine mode:", *combine)
+		}
+		if *backend != "cgo" || *lazyInit || *split || *extTags {
+			log.Fatalln("lazyInit, split, extTags, and the webgl backend are not supported by combined packages")
+		}
+	}
+
+	minVersion, version, err := ParseVersionRange(vers[0])
 	if err != nil {
 		log.Fatalln("error parsing version:", err)
 	}
+	if minVersion != (Version{}) && len(apis) > 1 {
+		// Init requires functions by the API of the context instead
+		log.Fatalln("version ranges are not supported by combined packages")
+	}
+	if minVersion != (Version{}) && (*api == "vulkan" || *backend == "webgl") {
+		// Vulkan loads most functions after Init, WebGL has a single version
+		log.Fatalln("version ranges are not supported for vulkan packages or the webgl backend")
+	}
+
+	if *lazyInit && *api == "vulkan" {
+		// Vulkan functions are loaded per instance and device, see InitInstance
+		log.Fatalln("lazyInit is not supported for vulkan packages")
+	}
+
+	if *aliasFall && (*lazyInit || *api == "vulkan" || *backend == "webgl") {
+		// Vulkan promotes commands through its own alias attribute
+		log.Fatalln("aliasFallback is not supported with lazyInit, vulkan packages, or the webgl backend")
+	}
+
+	if *headless && *api != "egl" {
+		log.Fatalln("headless is only supported for egl packages")
+	}
+
+	switch *backend {
+	case "cgo":
+	case "webgl":
+		// WebGL 2 implements a subset of OpenGL ES 3.0 without extensions
+		if *api != "gles2" || version.IsAll() || version.Compare(Version{3, 0}) > 0 {
+			log.Fatalln("the webgl backend requires -api=gles2 and a version up to 3.0")
+		}
+		if *lazyInit || *split || *extTags {
+			log.Fatalln("lazyInit, split, and extTags are not supported by the webgl backend")
+		}
+	default:
+		log.Fatalln("unknown backend:", *backend)
+	}
 
 	var addExtRegexp *regexp.Regexp = nil
 	if *addext != "" {
@@ -53,13 +124,32 @@ func generate(name string, args []string) {
 	}
 
 	packageSpec := &PackageSpec{
-		API:          *api,
-		Version:      version,
-		Profile:      *profile,
-		TmplDir:      *tmplDir,
-		AddExtRegexp: addExtRegexp,
-		RemExtRegexp: remExtRegexp,
-		LenientInit:  *lenientInit,
+		API:           apis[0],
+		Version:       version,
+		MinVersion:    minVersion,
+		Profile:       profiles[0],
+		TmplDir:       *tmplDir,
+		Backend:       *backend,
+		AddExtRegexp:  addExtRegexp,
+		RemExtRegexp:  remExtRegexp,
+		LenientInit:   *lenientInit,
+		SplitFiles:    *split,
+		ExtensionTags: *extTags,
+		StrictVersion: *strictVer,
+		LazyInit:      *lazyInit,
+		AliasFallback: *aliasFall,
+		Headless:      *headless,
+	}
+
+	packageSpecs := []*PackageSpec{packageSpec}
+	for i := 1; i < len(apis); i++ {
+		version, err := ParseVersion(vers[i])
+		if err != nil {
+			log.Fatalln("error parsing version:", err)
+		}
+		apiSpec := *packageSpec
+		apiSpec.API, apiSpec.Version, apiSpec.Profile = apis[i], version, profiles[i]
+		packageSpecs = append(packageSpecs, &apiSpec)
 	}
 
 	specs := parseSpecifications(*xmlDir)
@@ -67,15 +157,30 @@ func generate(name string, args []string) {
 
 	var pkg *Package
 	for _, spec := range specs {
-		if spec.HasPackage(packageSpec) {
-			pkg = spec.ToPackage(packageSpec)
+		if hasPackages(spec, packageSpecs) {
+			if len(packageSpecs) > 1 {
+				pkg = spec.ToCombinedPackage(packageSpecs, *combine == "union")
+			} else {
+				pkg = spec.ToPackage(packageSpec)
+			}
+			if packageSpec.Headless && !pkg.HasHeadlessContext() {
+				log.Fatalln("headless requires the EGL 1.5 functions, e.g., eglGetPlatformDisplay")
+			}
 			docs.AddDocs(pkg)
 			if len(*restrict) > 0 {
 				performRestriction(pkg, *restrict)
 			}
+			if *check {
+				checkPackage(pkg, *xmlDir, *outDir)
+				return
+			}
 			if err := pkg.GeneratePackage(*outDir); err != nil {
 				log.Fatalln("error generating package:", err)
 			}
+			if pkg.IsWebGL() {
+				// WebGL packages do not include C headers
+				break
+			}
 			if err := copyIncludes(filepath.Join(*xmlDir, "include"), *outDir); err != nil {
 				log.Fatalln("error copying includes:", err)
 			}
@@ -88,6 +193,50 @@ func generate(name string, args []string) {
 	log.Println("generated package in", *outDir)
 }
 
+// isCombinableAPI returns whether packages of the API may be combined with
+// those of other APIs. They must query the version of the context to
+// determine its API, and thus only OpenGL APIs qualify.
+func isCombinableAPI(api string) bool {
+	switch api {
+	case "gl", "gles1", "gles2", "glsc2":
+		return true
+	}
+	return false
+}
+
+// hasPackages returns whether the specification can generate all the
+// packages.
+func hasPackages(spec *Specification, pkgSpecs []*PackageSpec) bool {
+	for _, pkgSpec := range pkgSpecs {
+		if !spec.HasPackage(pkgSpec) {
+			return false
+		}
+	}
+	return true
+}
+
+// Compares the package and includes that would be generated against outDir,
+// printing a unified diff and exiting non-zero if outDir is out of date.
+func checkPackage(pkg *Package, xmlDir, outDir string) {
+	diff, err := pkg.CheckPackage(outDir)
+	if err != nil {
+		log.Fatalln("error checking package:", err)
+	}
+	if !pkg.IsWebGL() {
+		includesDiff, err := checkIncludes(filepath.Join(xmlDir, "include"), outDir)
+		if err != nil {
+			log.Fatalln("error checking includes:", err)
+		}
+		diff += includesDiff
+	}
+	if diff != "" {
+		fmt.Print(diff)
+		log.Println("package in", outDir, "is out of date")
+		os.Exit(1)
+	}
+	log.Println("package in", outDir, "is up to date")
+}
+
 // Attempt to determine the base directory of go-gl/glow. This only works in case of non-module-aware
 // cases and acts as a backwards compatible way.
 //
@@ -210,6 +359,33 @@ func copyIncludes(srcDir, dstDir string) error {
 	return nil
 }
 
+func checkIncludes(srcDir, dstDir string) (string, error) {
+	files, err := ioutil.ReadDir(srcDir)
+	if err != nil {
+		return "", err
+	}
+	var diffs strings.Builder
+	for _, file := range files {
+		srcName := filepath.Join(srcDir, file.Name())
+		dstName := filepath.Join(dstDir, file.Name())
+		switch {
+		case file.IsDir():
+			diff, err := checkIncludes(srcName, dstName)
+			if err != nil {
+				return "", err
+			}
+			diffs.WriteString(diff)
+		case file.Size() > 0:
+			data, err := ioutil.ReadFile(srcName)
+			if err != nil {
+				return "", err
+			}
+			diffs.WriteString(diffFile(dstName, data))
+		}
+	}
+	return diffs.String(), nil
+}
+
 func copyFile(srcFile, dstFile string) error {
 	out, err := os.Create(dstFile)
 	if err != nil {
@@ -229,13 +405,21 @@ func copyFile(srcFile, dstFile string) error {
 
 // PackageSpec describes a package to be generated.
 type PackageSpec struct {
-	API          string
-	Version      Version
-	Profile      string // If "all" overrides the version spec
-	TmplDir      string
-	AddExtRegexp *regexp.Regexp
-	RemExtRegexp *regexp.Regexp
-	LenientInit  bool
+	API           string
+	Version       Version
+	MinVersion    Version // Version up to which Init requires functions, if a range of versions
+	Profile       string  // If "all" overrides the version spec
+	TmplDir       string
+	Backend       string // "cgo" or "webgl"
+	AddExtRegexp  *regexp.Regexp
+	RemExtRegexp  *regexp.Regexp
+	LenientInit   bool
+	SplitFiles    bool
+	ExtensionTags bool
+	StrictVersion bool
+	LazyInit      bool
+	AliasFallback bool
+	Headless      bool
 }
 
 func printUsage(name string) {
@@ -243,6 +427,7 @@ func printUsage(name string) {
 	fmt.Println("Commands:")
 	fmt.Println("  download  Downloads specification and documentation XML files")
 	fmt.Println("  generate  Generates bindings")
+	fmt.Println("  changes   Reports changes between two specification snapshots")
 	fmt.Printf("Use %s <command> -help for a detailed command description\n", name)
 }
 
@@ -261,6 +446,8 @@ func main() {
 		download("download", args[1:])
 	case "generate":
 		generate("generate", args[1:])
+	case "changes":
+		changes("changes", args[1:])
 	default:
 		fmt.Printf("Unknown command: '%s'\n", command)
 		printUsage(name)
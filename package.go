@@ -1,11 +1,16 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"go/format"
+	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
 
@@ -14,15 +19,34 @@ import (
 
 // A Package holds the typedef, function, and enum definitions for a Go package.
 type Package struct {
-	Name    string
-	API     string
-	Version Version
-	Profile string
-	TmplDir string
+	Name       string
+	API        string
+	Version    Version
+	MinVersion Version // Version up to which Init requires functions, if Version is the end of a range
+	Profile    string
+	TmplDir    string
+	Backend    string // Implementation of the functions, "cgo" or "webgl"
 
-	Typedefs  []*Typedef
-	Enums     map[string]*Enum
-	Functions map[string]*PackageFunction
+	SplitFiles    bool // Generate one file per feature version and extension
+	ExtensionTags bool // Guard extension files with glow_no_<extension> build tags
+	StrictVersion bool // Fail Init if the context version or profile does not match
+	LazyInit      bool // Load functions on first use rather than in Init
+	AliasFallback bool // Bind the aliases of functions missing from the context
+	Headless      bool // Include NewHeadlessContext in EGL packages
+
+	Typedefs   []*Typedef
+	Enums      map[string]*Enum
+	Functions  map[string]*PackageFunction
+	Extensions []*PackageExtension // Extensions the package was generated with, ordered by name
+
+	Combined []*Package // Packages of the APIs a combined package merges, if any
+	Union    bool       // Whether a combined package holds the union rather than the intersection
+}
+
+// A PackageExtension describes an extension included in a package.
+type PackageExtension struct {
+	Name   string // Raw specification name
+	GoName string // Go name with the API prefix stripped
 }
 
 // A PackageFunction is a package-specific Function wrapper.
@@ -30,6 +54,148 @@ type PackageFunction struct {
 	Function
 	Required bool
 	Doc      string
+
+	Version         Version  // Feature version that introduced the function, if any
+	Extension       string   // Extension that introduced the function, if any
+	OtherExtensions []string // Other extensions adding the function, if any
+	Fallbacks       []string // Aliases Init binds if the function is missing, by preference
+	Removed         *Removal // Removal of the function by a profile the package does not target, if any
+
+	// APIs holds, for each package merged into a combined package, how its API
+	// provides the function, or nil if it lacks the function.
+	APIs []*FunctionAPI
+}
+
+// A FunctionAPI describes how an API of a combined package provides a
+// function.
+type FunctionAPI struct {
+	API       string
+	Required  bool    // Whether Init fails if the function is missing from a context of the API
+	Version   Version // Feature version that introduced the function, if any
+	Extension string  // Extension that introduced the function, if any
+}
+
+// A PackageGroup holds the enums and functions of a package introduced by a
+// single feature version or extension, or added by each of several extensions.
+type PackageGroup struct {
+	*Package // The package restricted to the enums and functions of the group
+
+	Version         Version  // Feature version of the group, if not an extension
+	Extension       string   // Extension name of the group, if any
+	OtherExtensions []string // Other extensions adding the enums and functions of the group, if any
+}
+
+// An EnumValue lists the names of the enums sharing a value.
+type EnumValue struct {
+	Value uint32
+	Names []string // Go names, those of core enums before those of extensions
+}
+
+// An ErrorCode describes an error code reported by glGetError.
+type ErrorCode struct {
+	GoName  string // Go name of the enum, e.g., "INVALID_ENUM"
+	ErrName string // Go name of the generated error, e.g., "ErrInvalidEnum"
+}
+
+// errorCodes lists the standard error codes reported by glGetError and
+// eglGetError.
+var errorCodes = []struct{ name, errName string }{
+	{"GL_INVALID_ENUM", "ErrInvalidEnum"},
+	{"GL_INVALID_VALUE", "ErrInvalidValue"},
+	{"GL_INVALID_OPERATION", "ErrInvalidOperation"},
+	{"GL_STACK_OVERFLOW", "ErrStackOverflow"},
+	{"GL_STACK_UNDERFLOW", "ErrStackUnderflow"},
+	{"GL_OUT_OF_MEMORY", "ErrOutOfMemory"},
+	{"GL_INVALID_FRAMEBUFFER_OPERATION", "ErrInvalidFramebufferOperation"},
+	{"GL_CONTEXT_LOST", "ErrContextLost"},
+	{"GL_TABLE_TOO_LARGE", "ErrTableTooLarge"},
+	{"EGL_NOT_INITIALIZED", "ErrNotInitialized"},
+	{"EGL_BAD_ACCESS", "ErrBadAccess"},
+	{"EGL_BAD_ALLOC", "ErrBadAlloc"},
+	{"EGL_BAD_ATTRIBUTE", "ErrBadAttribute"},
+	{"EGL_BAD_CONFIG", "ErrBadConfig"},
+	{"EGL_BAD_CONTEXT", "ErrBadContext"},
+	{"EGL_BAD_CURRENT_SURFACE", "ErrBadCurrentSurface"},
+	{"EGL_BAD_DISPLAY", "ErrBadDisplay"},
+	{"EGL_BAD_MATCH", "ErrBadMatch"},
+	{"EGL_BAD_NATIVE_PIXMAP", "ErrBadNativePixmap"},
+	{"EGL_BAD_NATIVE_WINDOW", "ErrBadNativeWindow"},
+	{"EGL_BAD_PARAMETER", "ErrBadParameter"},
+	{"EGL_BAD_SURFACE", "ErrBadSurface"},
+	{"EGL_CONTEXT_LOST", "ErrContextLost"},
+}
+
+// A packageFile describes a generated Go file and the template rendering it.
+type packageFile struct {
+	name string      // File name without the .go suffix
+	tmpl string      // Template name without the .tmpl suffix
+	data interface{} // Template data
+}
+
+// SetsDebugCallback returns whether the function registers a debug callback,
+// e.g., glDebugMessageCallback.
+func (f *PackageFunction) SetsDebugCallback() bool {
+	return len(f.Parameters) == 2 && f.Parameters[0].Type.IsDebugProc()
+}
+
+// Dispatch returns the Vulkan dispatch level of the function: "Global" for
+// functions loaded without an instance, "Instance" for those loaded through
+// vkGetInstanceProcAddr, and "Device" for those loaded through
+// vkGetDeviceProcAddr.
+func (f *PackageFunction) Dispatch() string {
+	switch f.Name {
+	case "vkGetInstanceProcAddr":
+		return "Global"
+	case "vkGetDeviceProcAddr":
+		return "Instance"
+	}
+	if len(f.Parameters) == 0 || f.Parameters[0].Type.Category != "handle" {
+		return "Global"
+	}
+	switch f.Parameters[0].Type.Name {
+	case "VkInstance", "VkPhysicalDevice":
+		return "Instance"
+	}
+	return "Device"
+}
+
+// SetsCallback returns whether the function registers a callback, e.g.,
+// glDebugMessageCallbackAMD.
+func (f *PackageFunction) SetsCallback() bool {
+	for _, p := range f.Parameters {
+		if p.Type.IsCallback() {
+			return true
+		}
+	}
+	return false
+}
+
+// UserParamCallback returns the callback parameter receiving the user data
+// passed to the function, or nil if there is none.
+func (f *PackageFunction) UserParamCallback() *Parameter {
+	for i, p := range f.Parameters {
+		if p.Type.IsCallback() && p.Type.Callback.HasUserParam() {
+			return &f.Parameters[i]
+		}
+	}
+	return nil
+}
+
+// IsCallbackUserParam returns whether the parameter passes user data through
+// to a callback.
+func (f *PackageFunction) IsCallbackUserParam(p Parameter) bool {
+	return p.IsUserParam() && f.UserParamCallback() != nil
+}
+
+// CallbackUserParam returns the parameter passing user data through to a
+// callback, or nil if there is none.
+func (f *PackageFunction) CallbackUserParam() *Parameter {
+	for i, p := range f.Parameters {
+		if f.IsCallbackUserParam(p) {
+			return &f.Parameters[i]
+		}
+	}
+	return nil
 }
 
 // Comment returns the comment explaining the function.
@@ -51,9 +217,73 @@ func (f *PackageFunction) Comment() string {
 		lines = append(lines, fmt.Sprintf("// Return value has type %s.", r.GoCType()))
 	}
 
+	if len(f.APIs) > 0 {
+		var labels []string
+		for _, api := range f.APIs {
+			if api != nil {
+				labels = append(labels, api.Label())
+			}
+		}
+		lines = append(lines, fmt.Sprintf("// Provided by %s.", strings.Join(labels, ", ")))
+	}
+
+	if f.SetsDebugCallback() {
+		lines = append(lines,
+			"// The callback receives userParam, which may be any Go value. Each context",
+			"// keeps its own callback; a nil callback unregisters the current one.")
+	} else if p := f.CallbackUserParam(); p != nil {
+		lines = append(lines, fmt.Sprintf("// The callback receives %s, which may be any Go value.", p.GoName()))
+	} else if f.SetsCallback() {
+		lines = append(lines,
+			"// Callbacks are shared by all contexts; registering a callback replaces",
+			"// the one previously registered through this function.")
+	}
+
+	if f.Removed != nil {
+		if len(lines) > 0 {
+			// The notice must be a paragraph of its own, whose separating line
+			// the generated code would otherwise lose
+			lines = append(lines, "//glow:keepspace", "//", "//glow:rmspace")
+		}
+		lines = append(lines, "// "+f.Removed.Deprecation())
+	}
+
 	return strings.Join(lines, "\n")
 }
 
+// ProvidedAPIs returns the bit mask of the packages merged into a combined
+// package that provide the function, e.g., 0b01 for the first.
+func (f *PackageFunction) ProvidedAPIs() uint32 {
+	var mask uint32
+	for i, api := range f.APIs {
+		if api != nil {
+			mask |= 1 << uint(i)
+		}
+	}
+	return mask
+}
+
+// RequiredAPIs returns the bit mask of the packages merged into a combined
+// package that require the function.
+func (f *PackageFunction) RequiredAPIs() uint32 {
+	var mask uint32
+	for i, api := range f.APIs {
+		if api != nil && api.Required {
+			mask |= 1 << uint(i)
+		}
+	}
+	return mask
+}
+
+// Label returns a human-readable name of the feature version or extension
+// providing the function, e.g., "gles2 3.0" or "GL_OES_mapbuffer (gles2)".
+func (a *FunctionAPI) Label() string {
+	if a.Extension != "" {
+		return fmt.Sprintf("%s (%s)", a.Extension, a.API)
+	}
+	return fmt.Sprintf("%s %s", a.API, a.Version)
+}
+
 // UniqueName returns a globally unique Go-compatible name for this package.
 func (pkg *Package) UniqueName() string {
 	version := strings.Replace(pkg.Version.String(), ".", "", -1)
@@ -66,44 +296,374 @@ func (pkg *Package) GeneratePackage(dir string) error {
 		return err
 	}
 
-	if err := pkg.generateFile("package", dir); err != nil {
-		return err
-	}
-	if err := pkg.generateFile("conversions", dir); err != nil {
-		return err
+	files := pkg.files()
+	for _, file := range files {
+		if err := pkg.generateFile(file, dir); err != nil {
+			return err
+		}
 	}
-	if err := pkg.generateFile("procaddr", dir); err != nil {
+
+	stale, err := staleFiles(dir, files)
+	if err != nil {
 		return err
 	}
-	if pkg.HasDebugCallbackFeature() {
-		if err := pkg.generateFile("debug", dir); err != nil {
+	for _, filename := range stale {
+		if err := os.Remove(filename); err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
-	// gofmt the generated .go files.
-	if err := exec.Command("gofmt", "-w", dir).Run(); err != nil {
-		return fmt.Errorf("gofmt error: %v", err)
+// CheckPackage renders the Go package in memory and compares it against the
+// files in the specified directory. It returns a unified diff describing how
+// the directory differs from the generated package, or an empty string if the
+// directory is up to date. Files glow generated before that the package no
+// longer includes are removed by the diff, see staleFiles.
+func (pkg *Package) CheckPackage(dir string) (string, error) {
+	var diffs strings.Builder
+	files := pkg.files()
+	for _, file := range files {
+		src, err := pkg.renderFile(file)
+		if err != nil {
+			return "", err
+		}
+		path := filepath.Join(dir, file.name+".go")
+		diffs.WriteString(diffFile(path, src))
 	}
 
-	return nil
+	stale, err := staleFiles(dir, files)
+	if err != nil {
+		return "", err
+	}
+	for _, filename := range stale {
+		diffs.WriteString(staleFileDiff(filename))
+	}
+	return diffs.String(), nil
+}
+
+// generatedHeaderRegexp matches the comment marking the files glow generates.
+var generatedHeaderRegexp = regexp.MustCompile(`(?m)^// Code generated by glow .*DO NOT EDIT\.$`)
+
+// staleFiles returns the paths of the Go files of dir that glow generated but
+// that are not among files, e.g., those of a previous generation with -split.
+// Files without the header of generated files, e.g., hand-written ones, are
+// never stale.
+func staleFiles(dir string, files []packageFile) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	generated := make(map[string]bool, len(files))
+	for _, file := range files {
+		generated[file.name+".go"] = true
+	}
+	var stale []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") || generated[entry.Name()] {
+			continue
+		}
+		filename := filepath.Join(dir, entry.Name())
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		// The header precedes the package clause
+		if i := bytes.Index(src, []byte("\npackage ")); i >= 0 {
+			src = src[:i]
+		}
+		if generatedHeaderRegexp.Match(src) {
+			stale = append(stale, filename)
+		}
+	}
+	return stale, nil
+}
+
+// files returns the Go files generated for this package.
+func (pkg *Package) files() []packageFile {
+	if pkg.IsWebGL() {
+		return pkg.webglFiles()
+	}
+	files := []packageFile{
+		{name: "package", tmpl: "package", data: pkg},
+		{name: "conversions", tmpl: "conversions", data: pkg},
+		{name: "procaddr", tmpl: "procaddr", data: pkg},
+	}
+	if len(pkg.Callbacks()) > 0 {
+		files = append(files, packageFile{name: "callbacks", tmpl: "callbacks", data: pkg})
+	}
+	if pkg.HasDebugCallbackFeature() {
+		files = append(files, packageFile{name: "debug", tmpl: "debug", data: pkg})
+	}
+	if pkg.HasExtensionQuery() {
+		files = append(files, packageFile{name: "extensions", tmpl: "extensions", data: pkg})
+	}
+	if len(pkg.EnumValues()) > 0 {
+		files = append(files, packageFile{name: "enumnames", tmpl: "enumnames", data: pkg})
+	}
+	if pkg.HasHalfType() {
+		files = append(files, packageFile{name: "half", tmpl: "half", data: pkg})
+	}
+	if pkg.HasErrorQuery() {
+		files = append(files, packageFile{name: "errors", tmpl: "errors", data: pkg})
+	}
+	if pkg.HasVersionQuery() {
+		files = append(files, packageFile{name: "version", tmpl: "version", data: pkg})
+	}
+	if len(pkg.Structs()) > 0 {
+		files = append(files, packageFile{name: "structs", tmpl: "structs", data: pkg})
+	}
+	if pkg.IsVK() {
+		files = append(files, packageFile{name: "vulkan", tmpl: "vulkan", data: pkg})
+	}
+	if pkg.IsWindowSystem() {
+		files = append(files, packageFile{name: "windowsystem", tmpl: "windowsystem", data: pkg})
+	}
+	if pkg.HasHeadlessContext() {
+		files = append(files, packageFile{name: "headless", tmpl: "headless", data: pkg})
+	}
+	if pkg.SplitFiles {
+		for _, group := range pkg.Groups() {
+			files = append(files, packageFile{name: group.FileName(), tmpl: "group", data: group})
+		}
+	}
+	return files
+}
+
+// webglFiles returns the Go files generated for this package with the webgl
+// backend, all of which are restricted to js/wasm.
+func (pkg *Package) webglFiles() []packageFile {
+	files := []packageFile{
+		{name: "package", tmpl: "webgl", data: pkg},
+		{name: "conversions", tmpl: "webglconversions", data: pkg},
+	}
+	if len(pkg.EnumValues()) > 0 {
+		files = append(files, packageFile{name: "enumnames", tmpl: "enumnames", data: pkg})
+	}
+	if pkg.HasErrorQuery() {
+		files = append(files, packageFile{name: "errors", tmpl: "errors", data: pkg})
+	}
+	return files
 }
 
-func (pkg *Package) generateFile(file, dir string) error {
-	out, err := os.Create(filepath.Join(dir, file+".go"))
+func (pkg *Package) generateFile(file packageFile, dir string) error {
+	src, err := pkg.renderFile(file)
 	if err != nil {
 		return err
 	}
-	defer out.Close()
+	return writeFileAtomic(filepath.Join(dir, file.name+".go"), src, 0644)
+}
 
+// renderFile executes the file's template and returns the gofmt-ed result.
+func (pkg *Package) renderFile(file packageFile) ([]byte, error) {
 	fns := template.FuncMap{
 		"replace": strings.Replace,
 		"toUpper": strings.ToUpper,
 	}
 
-	tmpl := template.Must(template.New(file + ".tmpl").Funcs(fns).ParseFiles(filepath.Join(pkg.TmplDir, file+".tmpl")))
+	// Group files share their definitions with the package template
+	tmplFiles := []string{filepath.Join(pkg.TmplDir, file.tmpl+".tmpl")}
+	if file.tmpl != "package" {
+		tmplFiles = append(tmplFiles, filepath.Join(pkg.TmplDir, "package.tmpl"))
+	}
+	tmpl := template.Must(template.New(file.tmpl + ".tmpl").Funcs(fns).ParseFiles(tmplFiles...))
 
-	return tmpl.Execute(NewBlankLineStrippingWriter(out), pkg)
+	var out bytes.Buffer
+	if err := tmpl.Execute(NewBlankLineStrippingWriter(&out), file.data); err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("gofmt error in %s.go: %v", file.name, err)
+	}
+	return src, nil
+}
+
+// EnumValues returns the enums of the package grouped and ordered by value.
+// Enums whose values do not fit into a uint32 are left out.
+func (pkg *Package) EnumValues() []*EnumValue {
+	byValue := make(map[uint32][]*Enum)
+	for _, enum := range pkg.Enums {
+		value, err := strconv.ParseUint(enum.Value, 0, 32)
+		if err != nil {
+			continue
+		}
+		byValue[uint32(value)] = append(byValue[uint32(value)], enum)
+	}
+
+	values := make([]*EnumValue, 0, len(byValue))
+	for value, enums := range byValue {
+		sort.Slice(enums, func(i, j int) bool {
+			if (enums[i].Extension == "") != (enums[j].Extension == "") {
+				return enums[i].Extension == ""
+			}
+			return enums[i].GoName < enums[j].GoName
+		})
+		names := make([]string, len(enums))
+		for i, enum := range enums {
+			names[i] = enum.GoName
+		}
+		values = append(values, &EnumValue{Value: uint32(value), Names: names})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Value < values[j].Value })
+	return values
+}
+
+// Groups partitions the enums and functions of the package by the feature
+// version or extension that introduced them. With ExtensionTags, enums and
+// functions added by several extensions form a group of their own, which is
+// kept unless all of the extensions are omitted. Feature groups come first in
+// version order, followed by extension groups in name order.
+func (pkg *Package) Groups() []*PackageGroup {
+	groups := make(map[string]*PackageGroup)
+	groupFor := func(api string, version Version, extension string, others []string) *PackageGroup {
+		if !pkg.ExtensionTags {
+			others = nil
+		}
+		key := strings.Join(append([]string{extension}, others...), " ")
+		if extension == "" {
+			key = api + " " + version.String()
+		}
+		group, ok := groups[key]
+		if !ok {
+			sub := *pkg
+			sub.API = api
+			sub.Enums = make(map[string]*Enum)
+			sub.Functions = make(map[string]*PackageFunction)
+			group = &PackageGroup{Package: &sub, Version: version, Extension: extension, OtherExtensions: others}
+			groups[key] = group
+		}
+		return group
+	}
+	for name, enum := range pkg.Enums {
+		groupFor(pkg.API, enum.Version, enum.Extension, enum.OtherExtensions).Enums[name] = enum
+	}
+	for name, fn := range pkg.Functions {
+		// Functions of combined packages belong to the first API providing them
+		api := pkg.API
+		for _, fnAPI := range fn.APIs {
+			if fnAPI != nil {
+				api = fnAPI.API
+				break
+			}
+		}
+		groupFor(api, fn.Version, fn.Extension, fn.OtherExtensions).Functions[name] = fn
+	}
+
+	sorted := make([]*PackageGroup, 0, len(groups))
+	for _, group := range groups {
+		sorted = append(sorted, group)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		gi, gj := sorted[i], sorted[j]
+		if (gi.Extension == "") != (gj.Extension == "") {
+			return gi.Extension == ""
+		}
+		if gi.Extension == "" {
+			if gi.API != gj.API {
+				return pkg.apiIndex(gi.API) < pkg.apiIndex(gj.API)
+			}
+			return gi.Version.Compare(gj.Version) < 0
+		}
+		if gi.Extension != gj.Extension {
+			return gi.Extension < gj.Extension
+		}
+		return strings.Join(gi.OtherExtensions, " ") < strings.Join(gj.OtherExtensions, " ")
+	})
+	return sorted
+}
+
+// FileName returns the name of the Go file, without the .go suffix, holding
+// the group's enums and functions.
+func (g *PackageGroup) FileName() string {
+	if g.Extension != "" {
+		extensions := append([]string{g.Extension}, g.OtherExtensions...)
+		return goFileName(strings.ToLower(strings.Join(extensions, "_")))
+	}
+	return goFileName(fmt.Sprintf("%s_%d_%d", g.API, g.Version.Major, g.Version.Minor))
+}
+
+// BuildConstraint returns the build constraint expression which keeps the
+// group in the build unless the tags of all its extensions are set, e.g.,
+// "!glow_no_GL_ARB_sync". Only extension groups can be omitted.
+func (g *PackageGroup) BuildConstraint() string {
+	if !g.ExtensionTags || g.Extension == "" {
+		return ""
+	}
+	constraint := "!glow_no_" + g.Extension
+	for _, extension := range g.OtherExtensions {
+		constraint += " || !glow_no_" + extension
+	}
+	return constraint
+}
+
+// Label returns a human-readable name of the group, e.g., "gl 3.3" or
+// "GL_ARB_sync".
+func (g *PackageGroup) Label() string {
+	if g.Extension != "" {
+		return g.Extension
+	}
+	return fmt.Sprintf("%s %s", g.API, g.Version)
+}
+
+// Callbacks returns the function pointer types the package can call back into,
+// i.e., those accepted by its functions, ordered by name.
+func (pkg *Package) Callbacks() []*Callback {
+	byName := make(map[string]*Callback)
+	for _, fn := range pkg.Functions {
+		for _, p := range fn.Parameters {
+			if p.Type.IsCallback() {
+				byName[p.Type.Callback.Name] = p.Type.Callback
+			}
+		}
+	}
+	callbacks := make([]*Callback, 0, len(byName))
+	for _, callback := range byName {
+		callbacks = append(callbacks, callback)
+	}
+	sort.Slice(callbacks, func(i, j int) bool { return callbacks[i].Name < callbacks[j].Name })
+	return callbacks
+}
+
+// CallbackTypes returns the callbacks of the package with distinct Go types.
+func (pkg *Package) CallbackTypes() []*Callback {
+	var types []*Callback
+	seen := make(map[string]bool)
+	for _, callback := range pkg.Callbacks() {
+		if !seen[callback.GoName()] {
+			seen[callback.GoName()] = true
+			types = append(types, callback)
+		}
+	}
+	return types
+}
+
+// CallbacksUseUnsafe returns whether any callback of the package receives a
+// pointer that is passed as unsafe.Pointer or converted to a Go string.
+func (pkg *Package) CallbacksUseUnsafe() bool {
+	for _, callback := range pkg.Callbacks() {
+		for i, p := range callback.Parameters {
+			if i != callback.UserParam() && (p.Type.IsCString() || p.Type.GoCType() == "unsafe.Pointer") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasCallbackUserParams returns whether any callback of the package receives
+// user data.
+func (pkg *Package) HasCallbackUserParams() bool {
+	for _, callback := range pkg.Callbacks() {
+		if callback.HasUserParam() {
+			return true
+		}
+	}
+	return false
 }
 
 // HasDebugCallbackFeature returns whether this package exposes the ability to
@@ -120,15 +680,274 @@ func (pkg *Package) HasDebugCallbackFeature() bool {
 	return false
 }
 
-// HasRequiredFunctions returns true if at least one function in this package
-// is required.
-func (pkg *Package) HasRequiredFunctions() bool {
-	for _, fn := range pkg.Functions {
-		if fn.Required {
+// DebugCallbackQueries returns the functions able to query the debug callback
+// of the current context, glGetPointerv and glGetPointervKHR, in order of
+// preference.
+func (pkg *Package) DebugCallbackQueries() []*PackageFunction {
+	var queries []*PackageFunction
+	for _, name := range []string{"glGetPointerv", "glGetPointervKHR"} {
+		if pkg.HasFunction(name) {
+			queries = append(queries, pkg.Functions[name])
+		}
+	}
+	return queries
+}
+
+// debugLabelFunctions lists the KHR_debug functions wrapped by the Go string
+// helpers of debug.tmpl.
+var debugLabelFunctions = []string{"glObjectLabel", "glGetObjectLabel", "glPushDebugGroup", "glDebugMessageInsert"}
+
+// DebugLabelSuffixes returns the suffixes of the complete sets of KHR_debug
+// functions wrapped by the Go string helpers, in order of preference: "" for
+// the core functions and "KHR" for the extension functions of OpenGL ES.
+func (pkg *Package) DebugLabelSuffixes() []string {
+	var suffixes []string
+	for _, suffix := range []string{"", "KHR"} {
+		complete := true
+		for _, name := range debugLabelFunctions {
+			complete = complete && pkg.HasFunction(name+suffix)
+		}
+		if complete {
+			suffixes = append(suffixes, suffix)
+		}
+	}
+	return suffixes
+}
+
+// HasExtensionQuery returns whether the package can query the extensions
+// supported by the current context, either through glGetStringi or through
+// glGetString. Used to determine whether to include ExtensionSupported.
+func (pkg *Package) HasExtensionQuery() bool {
+	return pkg.HasEnum("GL_EXTENSIONS") && (pkg.HasFunction("glGetString") || pkg.HasIndexedExtensionQuery())
+}
+
+// HasIndexedExtensionQuery returns whether the package can query the
+// supported extensions one by one through glGetStringi.
+func (pkg *Package) HasIndexedExtensionQuery() bool {
+	return pkg.HasFunction("glGetStringi") && pkg.HasFunction("glGetIntegerv") &&
+		pkg.HasEnum("GL_EXTENSIONS") && pkg.HasEnum("GL_NUM_EXTENSIONS")
+}
+
+// HasHalfType returns whether the package deals in 16-bit floating point
+// numbers, i.e., declares GLhalf or the HALF_FLOAT data type. Used to
+// determine whether to include the Half type.
+func (pkg *Package) HasHalfType() bool {
+	for _, typedef := range pkg.Typedefs {
+		switch typedef.Name {
+		case "GLhalf", "GLhalfARB", "GLhalfNV", "cl_half":
 			return true
 		}
 	}
-	return false
+	return pkg.HasEnum("GL_HALF_FLOAT")
+}
+
+// HasErrorQuery returns whether the package can query errors through
+// glGetError or eglGetError. Used to determine whether to include the Error type.
+func (pkg *Package) HasErrorQuery() bool {
+	if pkg.IsEGL() {
+		return pkg.HasFunction("eglGetError") && pkg.HasEnum("EGL_SUCCESS")
+	}
+	return pkg.HasFunction("glGetError") && pkg.HasEnum("GL_NO_ERROR")
+}
+
+// typeErrorQuery makes glGetError and eglGetError return the Error type if the
+// package includes it.
+func (pkg *Package) typeErrorQuery() {
+	for _, name := range []string{"glGetError", "eglGetError"} {
+		if fn, ok := pkg.Functions[name]; ok {
+			fn.Return.Named = ""
+			if pkg.HasErrorQuery() {
+				fn.Return.Named = "Error"
+			}
+		}
+	}
+}
+
+// ErrorCodes returns the standard error codes included in the package.
+func (pkg *Package) ErrorCodes() []*ErrorCode {
+	var codes []*ErrorCode
+	for _, code := range errorCodes {
+		if pkg.HasEnum(code.name) {
+			codes = append(codes, &ErrorCode{GoName: pkg.Enums[code.name].GoName, ErrName: code.errName})
+		}
+	}
+	return codes
+}
+
+// markRemoved records the removal of the enums and functions of the package
+// removed by a feature version, keeping the earliest removal.
+func (pkg *Package) markRemoved(addRem *specAddRemSet, version Version) {
+	pkg.markRestored(addRem)
+	removal := &Removal{Version: version, Profile: addRem.profile}
+	for _, cmd := range addRem.removedCommands {
+		if fn, ok := pkg.Functions[cmd]; ok && fn.Removed == nil {
+			fn.Removed = removal
+		}
+	}
+	for _, name := range addRem.removedEnums {
+		if enum, ok := pkg.Enums[name]; ok && enum.Removed == nil {
+			enum.Removed = removal
+		}
+	}
+}
+
+// markRestored clears the removal of the enums and functions of the package
+// that a later feature version adds back to the profile removing them, e.g.,
+// glGetPointerv, removed by OpenGL 3.2 core and required again by 4.3.
+func (pkg *Package) markRestored(addRem *specAddRemSet) {
+	restores := func(removal *Removal) bool {
+		return removal != nil && (addRem.profile == "" || addRem.profile == removal.Profile)
+	}
+	for _, cmd := range addRem.addedCommands {
+		if fn, ok := pkg.Functions[cmd]; ok && restores(fn.Removed) {
+			fn.Removed = nil
+		}
+	}
+	for _, name := range addRem.addedEnums {
+		if enum, ok := pkg.Enums[name]; ok && restores(enum.Removed) {
+			enum.Removed = nil
+		}
+	}
+}
+
+// IsCombined returns whether the package merges the packages of several APIs,
+// see Specification.ToCombinedPackage.
+func (pkg *Package) IsCombined() bool {
+	return len(pkg.Combined) > 0
+}
+
+// apiIndex returns the index of the package of an API among those merged into
+// a combined package, or 0 if the package is not combined.
+func (pkg *Package) apiIndex(api string) int {
+	for i, sub := range pkg.Combined {
+		if sub.API == api {
+			return i
+		}
+	}
+	return 0
+}
+
+// HasVersionRange returns whether the package was generated for a range of
+// versions, whose versions after MinVersion Init loads leniently.
+func (pkg *Package) HasVersionRange() bool {
+	return pkg.MinVersion != (Version{})
+}
+
+// IsOptionalVersion returns whether Init loads the functions of a feature
+// version without requiring them, i.e., whether the version follows the
+// minimum version of a range.
+func (pkg *Package) IsOptionalVersion(version Version) bool {
+	return pkg.HasVersionRange() && version.Compare(pkg.MinVersion) > 0
+}
+
+// OptionalVersions returns the feature version groups following the minimum
+// version of a range, from the oldest.
+func (pkg *Package) OptionalVersions() []*PackageGroup {
+	var optional []*PackageGroup
+	for _, group := range pkg.Groups() {
+		if group.Extension == "" && pkg.IsOptionalVersion(group.Version) {
+			optional = append(optional, group)
+		}
+	}
+	return optional
+}
+
+// HasVersionQuery returns whether the package can query the version of the
+// current context through glGetString. Used to determine whether to include
+// ContextVersion.
+func (pkg *Package) HasVersionQuery() bool {
+	return pkg.HasFunction("glGetString") && pkg.HasEnum("GL_VERSION")
+}
+
+// HasIndexedVersionQuery returns whether the package can query the major and
+// minor version of the current context through glGetIntegerv.
+func (pkg *Package) HasIndexedVersionQuery() bool {
+	return pkg.HasFunction("glGetIntegerv") && pkg.HasEnum("GL_MAJOR_VERSION") && pkg.HasEnum("GL_MINOR_VERSION")
+}
+
+// HasProfileQuery returns whether the package can query the profile of the
+// current context through glGetIntegerv.
+func (pkg *Package) HasProfileQuery() bool {
+	return pkg.HasFunction("glGetIntegerv") && pkg.HasEnum("GL_CONTEXT_PROFILE_MASK") &&
+		pkg.HasEnum("GL_CONTEXT_CORE_PROFILE_BIT") && pkg.HasEnum("GL_CONTEXT_COMPATIBILITY_PROFILE_BIT")
+}
+
+// initFunctions lists the functions called by Init itself.
+var initFunctions = map[string]bool{
+	"glGetIntegerv": true,
+	"glGetString":   true,
+	"glGetStringi":  true,
+}
+
+// IsInitFunction returns whether Init itself calls the named function. Such
+// functions are loaded by Init even if the package loads functions lazily.
+func (pkg *Package) IsInitFunction(name string) bool {
+	return initFunctions[name]
+}
+
+// IsES returns whether the package targets OpenGL ES.
+func (pkg *Package) IsES() bool {
+	return strings.HasPrefix(pkg.API, "gles")
+}
+
+// IsSC returns whether the package targets OpenGL SC, the safety critical
+// subset of OpenGL ES.
+func (pkg *Package) IsSC() bool {
+	return pkg.API == "glsc2"
+}
+
+// IsCL returns whether the package targets OpenCL.
+func (pkg *Package) IsCL() bool {
+	return pkg.API == "opencl"
+}
+
+// IsVK returns whether the package targets Vulkan.
+func (pkg *Package) IsVK() bool {
+	return pkg.API == "vulkan"
+}
+
+// Structs returns the structures and unions of the package with a Go mirror,
+// in declaration order.
+func (pkg *Package) Structs() []*Typedef {
+	var structs []*Typedef
+	for _, typedef := range pkg.Typedefs {
+		if (typedef.Category == "struct" || typedef.Category == "union") &&
+			typedef.Base == "" && len(typedef.Members) > 0 {
+			structs = append(structs, typedef)
+		}
+	}
+	return structs
+}
+
+// StructAliases returns the typedefs of the package aliasing a structure or
+// union, e.g., VkPhysicalDeviceProperties2KHR.
+func (pkg *Package) StructAliases() []*Typedef {
+	mirrored := make(map[string]bool)
+	for _, typedef := range pkg.Structs() {
+		mirrored[typedef.Name] = true
+	}
+	var aliases []*Typedef
+	for _, typedef := range pkg.Typedefs {
+		if typedef.Base != "" && mirrored[typedef.Base] {
+			aliases = append(aliases, typedef)
+			mirrored[typedef.Name] = true
+		}
+	}
+	return aliases
+}
+
+// HasFunction returns whether the named function is always part of the
+// package, i.e., it is included and not guarded by an extension build tag.
+func (pkg *Package) HasFunction(name string) bool {
+	fn, ok := pkg.Functions[name]
+	return ok && (!pkg.ExtensionTags || fn.Extension == "")
+}
+
+// HasEnum returns whether the named enum is always part of the package,
+// i.e., it is included and not guarded by an extension build tag.
+func (pkg *Package) HasEnum(name string) bool {
+	enum, ok := pkg.Enums[name]
+	return ok && (!pkg.ExtensionTags || enum.Extension == "")
 }
 
 // Filter removes any enums, or functions found in this package that are not
@@ -154,6 +973,7 @@ func (pkg *Package) Filter(enums, functions map[string]bool) {
 			}
 		}
 	}
+	pkg.typeErrorQuery()
 }
 
 // importPathToDir resolves the absolute path from importPath.
@@ -0,0 +1,1324 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const packageTestRegistry = `<registry>
+  <types>
+    <type>typedef unsigned int <name>GLenum</name>;</type>
+    <type>typedef int <name>GLint</name>;</type>
+    <type>typedef unsigned int <name>GLuint</name>;</type>
+    <type>typedef int <name>GLsizei</name>;</type>
+    <type>typedef char <name>GLchar</name>;</type>
+    <type>typedef unsigned char <name>GLubyte</name>;</type>
+    <type>typedef unsigned short <name>GLhalfNV</name>;</type>
+    <type>typedef void (<apientry/> *<name>GLDEBUGPROC</name>)(GLenum source,GLenum type,GLuint id,GLenum severity,GLsizei length,const GLchar *message,const void *userParam);</type>
+  </types>
+  <enums>
+    <enum name="GL_ZERO" value="0"/>
+    <enum name="GL_ONE" value="1"/>
+    <enum name="GL_TEXTURE_2D" value="0x0DE1"/>
+    <enum name="GL_EXTENSIONS" value="0x1F03"/>
+    <enum name="GL_NUM_EXTENSIONS" value="0x821D"/>
+    <enum name="GL_VERSION" value="0x1F02"/>
+    <enum name="GL_NO_ERROR" value="0"/>
+    <enum name="GL_INVALID_ENUM" value="0x0500"/>
+    <enum name="GL_INVALID_OPERATION" value="0x0502"/>
+    <enum name="GL_MAJOR_VERSION" value="0x821B"/>
+    <enum name="GL_MINOR_VERSION" value="0x821C"/>
+    <enum name="GL_CONTEXT_PROFILE_MASK" value="0x9126"/>
+    <enum name="GL_CONTEXT_CORE_PROFILE_BIT" value="0x00000001"/>
+    <enum name="GL_CONTEXT_COMPATIBILITY_PROFILE_BIT" value="0x00000002"/>
+    <enum name="GL_FOO_BIT_EXT" value="0x00000001"/>
+  </enums>
+  <commands>
+    <command>
+      <proto>void <name>glBindTexture</name></proto>
+      <param><ptype>GLenum</ptype> <name>target</name></param>
+      <param><ptype>GLuint</ptype> <name>texture</name></param>
+    </command>
+    <command>
+      <proto><ptype>GLenum</ptype> <name>glGetError</name></proto>
+    </command>
+    <command>
+      <proto>void <name>glGetIntegerv</name></proto>
+      <param><ptype>GLenum</ptype> <name>pname</name></param>
+      <param><ptype>GLint</ptype> *<name>data</name></param>
+    </command>
+    <command>
+      <proto>const <ptype>GLubyte</ptype> *<name>glGetString</name></proto>
+      <param><ptype>GLenum</ptype> <name>name</name></param>
+    </command>
+    <command>
+      <proto>const <ptype>GLubyte</ptype> *<name>glGetStringi</name></proto>
+      <param><ptype>GLenum</ptype> <name>name</name></param>
+      <param><ptype>GLuint</ptype> <name>index</name></param>
+    </command>
+    <command>
+      <proto>void <name>glDeleteTextures</name></proto>
+      <param><ptype>GLsizei</ptype> <name>n</name></param>
+      <param>const <ptype>GLuint</ptype> *<name>textures</name></param>
+    </command>
+    <command>
+      <proto>void <name>glDebugMessageCallback</name></proto>
+      <param><ptype>GLDEBUGPROC</ptype> <name>callback</name></param>
+      <param>const void *<name>userParam</name></param>
+    </command>
+    <command>
+      <proto>void <name>glGetPointerv</name></proto>
+      <param><ptype>GLenum</ptype> <name>pname</name></param>
+      <param>void **<name>params</name></param>
+    </command>
+    <command>
+      <proto>void <name>glVertexAttrib1hNV</name></proto>
+      <param><ptype>GLuint</ptype> <name>index</name></param>
+      <param><ptype>GLhalfNV</ptype> <name>x</name></param>
+    </command>
+    <command>
+      <proto>void <name>glFooEXT</name></proto>
+      <param><ptype>GLint</ptype> <name>x</name></param>
+    </command>
+    <command>
+      <proto>void <name>glBindTextureEXT</name></proto>
+      <param><ptype>GLenum</ptype> <name>target</name></param>
+      <param><ptype>GLuint</ptype> <name>texture</name></param>
+      <alias name="glBindTexture"/>
+    </command>
+  </commands>
+  <feature api="gl" name="GL_VERSION_1_0" number="1.0">
+    <require>
+      <command name="glBindTexture"/>
+      <command name="glGetError"/>
+      <command name="glGetIntegerv"/>
+      <command name="glGetString"/>
+      <command name="glDeleteTextures"/>
+      <enum name="GL_ZERO"/>
+      <enum name="GL_ONE"/>
+      <enum name="GL_TEXTURE_2D"/>
+      <enum name="GL_EXTENSIONS"/>
+      <enum name="GL_VERSION"/>
+      <enum name="GL_NO_ERROR"/>
+      <enum name="GL_INVALID_ENUM"/>
+      <enum name="GL_INVALID_OPERATION"/>
+    </require>
+  </feature>
+  <feature api="gl" name="GL_VERSION_4_3" number="4.3">
+    <require>
+      <command name="glDebugMessageCallback"/>
+      <command name="glGetStringi"/>
+      <command name="glGetPointerv"/>
+      <enum name="GL_NUM_EXTENSIONS"/>
+      <enum name="GL_MAJOR_VERSION"/>
+      <enum name="GL_MINOR_VERSION"/>
+      <enum name="GL_CONTEXT_PROFILE_MASK"/>
+      <enum name="GL_CONTEXT_CORE_PROFILE_BIT"/>
+      <enum name="GL_CONTEXT_COMPATIBILITY_PROFILE_BIT"/>
+    </require>
+    <remove profile="core">
+      <command name="glDeleteTextures"/>
+      <enum name="GL_ZERO"/>
+    </remove>
+  </feature>
+  <feature api="gles2" name="GL_ES_VERSION_2_0" number="2.0">
+    <require>
+      <command name="glBindTexture"/>
+      <command name="glGetError"/>
+      <command name="glGetString"/>
+      <command name="glGetPointerv"/>
+      <command name="glFooEXT"/>
+      <enum name="GL_TEXTURE_2D"/>
+      <enum name="GL_VERSION"/>
+      <enum name="GL_ONE"/>
+    </require>
+  </feature>
+  <extensions>
+    <extension name="GL_EXT_foo" supported="gl">
+      <require>
+        <command name="glFooEXT"/>
+        <command name="glBindTextureEXT"/>
+        <enum name="GL_FOO_BIT_EXT"/>
+      </require>
+    </extension>
+    <extension name="GL_NV_half_float" supported="gl">
+      <require>
+        <type name="GLhalfNV"/>
+        <command name="glVertexAttrib1hNV"/>
+      </require>
+    </extension>
+  </extensions>
+</registry>`
+
+func newTestPackage(t *testing.T, pkgSpec *PackageSpec) *Package {
+	t.Helper()
+	spec := mustParseSpecification(t, packageTestRegistry)
+	if pkgSpec.TmplDir == "" {
+		pkgSpec.TmplDir = "tmpl"
+	}
+	if !spec.HasPackage(pkgSpec) {
+		t.Fatalf("test registry cannot generate package %v", pkgSpec)
+	}
+	return spec.ToPackage(pkgSpec)
+}
+
+// newRegistryPackage generates a package from the registry in filename.
+func newRegistryPackage(t *testing.T, filename string, pkgSpec *PackageSpec) *Package {
+	t.Helper()
+	registry, err := readSpecFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := NewSpecification(*registry, xmlOverloads{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgSpec.TmplDir = "tmpl"
+	if !spec.HasPackage(pkgSpec) {
+		t.Fatalf("%s cannot generate package %v", filename, pkgSpec)
+	}
+	return spec.ToPackage(pkgSpec)
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "glow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func readDir(t *testing.T, dir string) map[string][]byte {
+	t.Helper()
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string][]byte, len(infos))
+	for _, info := range infos {
+		data, err := ioutil.ReadFile(filepath.Join(dir, info.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[info.Name()] = data
+	}
+	return files
+}
+
+// generatePackage generates pkg into a temporary directory and returns the
+// contents of the generated files by name.
+func generatePackage(t *testing.T, pkg *Package) map[string][]byte {
+	t.Helper()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	if err := pkg.GeneratePackage(dir); err != nil {
+		t.Fatalf("GeneratePackage failed: %v", err)
+	}
+	return readDir(t, dir)
+}
+
+// TestGeneratePackageDeterministic generates packages several times, as the
+// files, groups, extensions, and enum names of a package are collected from
+// maps whose iteration order varies between runs.
+func TestGeneratePackageDeterministic(t *testing.T) {
+	for _, pkgSpec := range []*PackageSpec{
+		{API: "gl", Version: Version{4, 3}, SplitFiles: true, ExtensionTags: true},
+		{API: "gl", Version: Version{4, 3}, MinVersion: Version{1, 0}, AliasFallback: true},
+	} {
+		var first map[string][]byte
+		for i := 0; i < 5; i++ {
+			files := generatePackage(t, newTestPackage(t, pkgSpec))
+			if first == nil {
+				first = files
+				continue
+			}
+			if len(files) != len(first) {
+				t.Fatalf("generated %d and %d files", len(first), len(files))
+			}
+			for name, src := range first {
+				if !bytes.Equal(src, files[name]) {
+					t.Errorf("%s differs between runs", name)
+				}
+			}
+		}
+	}
+}
+
+func TestCheckPackage(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	pkg := newTestPackage(t, &PackageSpec{API: "gl", Version: Version{4, 3}})
+	if err := pkg.GeneratePackage(dir); err != nil {
+		t.Fatalf("GeneratePackage failed: %v", err)
+	}
+	if diff, err := pkg.CheckPackage(dir); err != nil || diff != "" {
+		t.Fatalf("expected freshly generated package to be up to date, got %q, %v", diff, err)
+	}
+
+	path := filepath.Join(dir, "package.go")
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := bytes.Replace(src, []byte("0x0DE1"), []byte("0x0DE2"), 1)
+	if err := ioutil.WriteFile(path, edited, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "conversions.go")); err != nil {
+		t.Fatal(err)
+	}
+	// Left over by generating with -split before, next to a hand-written file
+	for name, src := range map[string]string{
+		"gl_4_3.go": "// Code generated by glow (https://github.com/go-gl/glow). DO NOT EDIT.\n\npackage gl\n",
+		"doc.go":    "// Package gl is generated by glow.\npackage gl\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diff, err := pkg.CheckPackage(dir)
+	if err != nil {
+		t.Fatalf("CheckPackage failed: %v", err)
+	}
+	for _, expected := range []*regexp.Regexp{
+		regexp.MustCompile(`(?m)^-\tTEXTURE_2D += 0x0DE2$`),
+		regexp.MustCompile(`(?m)^\+\tTEXTURE_2D += 0x0DE1$`),
+		regexp.MustCompile(`(?m)^--- /dev/null\n\+\+\+ .*conversions\.go$`),
+		regexp.MustCompile(`(?m)^--- .*gl_4_3\.go\n\+\+\+ /dev/null\n@@ -1,3 \+0,0 @@\n-// Code generated by glow`),
+	} {
+		if !expected.MatchString(diff) {
+			t.Errorf("diff does not match %s:\n%s", expected, diff)
+		}
+	}
+	if strings.Contains(diff, "doc.go") {
+		t.Errorf("diff removes the hand-written doc.go:\n%s", diff)
+	}
+}
+
+func TestGeneratePackageRemovesStaleFiles(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	doc := filepath.Join(dir, "doc.go")
+	if err := ioutil.WriteFile(doc, []byte("package gl\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	split := newTestPackage(t, &PackageSpec{API: "gl", Version: Version{4, 3}, SplitFiles: true})
+	if err := split.GeneratePackage(dir); err != nil {
+		t.Fatalf("GeneratePackage failed: %v", err)
+	}
+	pkg := newTestPackage(t, &PackageSpec{API: "gl", Version: Version{4, 3}})
+	if err := pkg.GeneratePackage(dir); err != nil {
+		t.Fatalf("GeneratePackage failed: %v", err)
+	}
+
+	files := readDir(t, dir)
+	for _, name := range []string{"gl_1_0.go", "gl_4_3.go", "gl_ext_foo.go"} {
+		if _, ok := files[name]; ok {
+			t.Errorf("%s of the split package was not removed", name)
+		}
+	}
+	if _, ok := files["doc.go"]; !ok {
+		t.Errorf("hand-written doc.go was removed")
+	}
+	if diff, err := pkg.CheckPackage(dir); err != nil || diff != "" {
+		t.Errorf("expected regenerated package to be up to date, got %q, %v", diff, err)
+	}
+}
+
+func TestGeneratePackageSplitFiles(t *testing.T) {
+	pkg := newTestPackage(t, &PackageSpec{API: "gl", Version: Version{4, 3}, SplitFiles: true})
+	files := generatePackage(t, pkg)
+
+	expected := map[string][]string{
+		"gl_1_0.go":     {"func BindTexture(", "TEXTURE_2D", `group: "gl 1.0"`},
+		"gl_4_3.go":     {"func DebugMessageCallback(", "NUM_EXTENSIONS", `group: "gl 4.3"`},
+		"gl_ext_foo.go": {"func FooEXT(", `group: "GL_EXT_foo"`},
+	}
+	for name, contents := range expected {
+		src, ok := files[name]
+		if !ok {
+			t.Errorf("%s not generated", name)
+			continue
+		}
+		for _, content := range contents {
+			if !bytes.Contains(src, []byte(content)) {
+				t.Errorf("%s does not contain %q", name, content)
+			}
+		}
+	}
+	if bytes.Contains(files["package.go"], []byte("func BindTexture(")) {
+		t.Errorf("package.go contains functions of split files")
+	}
+}
+
+func TestGeneratePackageExtensionTags(t *testing.T) {
+	pkg := newTestPackage(t, &PackageSpec{API: "gl", Version: Version{4, 3}, ExtensionTags: true})
+	files := generatePackage(t, pkg)
+
+	if !bytes.Contains(files["gl_ext_foo.go"], []byte("//go:build !glow_no_GL_EXT_foo\n")) {
+		t.Errorf("extension file is not guarded by a build tag:\n%s", files["gl_ext_foo.go"])
+	}
+	if bytes.Contains(files["gl_1_0.go"], []byte("//go:build")) {
+		t.Errorf("feature file is guarded by a build tag:\n%s", files["gl_1_0.go"])
+	}
+	if !bytes.Contains(files["package.go"], []byte("glow_no_")) {
+		t.Errorf("package documentation does not describe the build tags")
+	}
+}
+
+func TestGeneratePackageSharedExtensionSymbols(t *testing.T) {
+	spec := mustParseSpecification(t, `<registry>
+  <types>
+    <type>typedef int <name>GLint</name>;</type>
+  </types>
+  <enums>
+    <enum name="GL_SHARED_EXT" value="0x1"/>
+  </enums>
+  <commands>
+    <command>
+      <proto>void <name>glSharedEXT</name></proto>
+      <param><ptype>GLint</ptype> <name>x</name></param>
+    </command>
+    <command>
+      <proto>void <name>glOwnEXT</name></proto>
+      <param><ptype>GLint</ptype> <name>x</name></param>
+    </command>
+  </commands>
+  <feature api="gl" name="GL_VERSION_1_0" number="1.0"/>
+  <extensions>
+    <extension name="GL_EXT_a" supported="gl">
+      <require>
+        <command name="glSharedEXT"/>
+        <command name="glOwnEXT"/>
+        <enum name="GL_SHARED_EXT"/>
+      </require>
+    </extension>
+    <extension name="GL_EXT_b" supported="gl">
+      <require>
+        <command name="glSharedEXT"/>
+        <enum name="GL_SHARED_EXT"/>
+      </require>
+    </extension>
+  </extensions>
+</registry>`)
+	pkg := spec.ToPackage(&PackageSpec{API: "gl", Version: Version{1, 0}, TmplDir: "tmpl", ExtensionTags: true})
+	for _, others := range [][]string{pkg.Functions["glSharedEXT"].OtherExtensions, pkg.Enums["GL_SHARED_EXT"].OtherExtensions} {
+		if !reflect.DeepEqual(others, []string{"GL_EXT_b"}) {
+			t.Errorf("shared symbol is also added by %v, want GL_EXT_b", others)
+		}
+	}
+
+	files := generatePackage(t, pkg)
+	for name, expected := range map[string][]string{
+		"gl_ext_a.go":          {"//go:build !glow_no_GL_EXT_a\n", "func OwnEXT("},
+		"gl_ext_a_gl_ext_b.go": {"//go:build !glow_no_GL_EXT_a || !glow_no_GL_EXT_b\n", "func SharedEXT(", "SHARED_EXT = 0x1"},
+	} {
+		for _, content := range expected {
+			if !bytes.Contains(files[name], []byte(content)) {
+				t.Errorf("%s does not contain %q", name, content)
+			}
+		}
+	}
+	if bytes.Contains(files["gl_ext_a.go"], []byte("SharedEXT")) {
+		t.Errorf("gl_ext_a.go contains the shared function")
+	}
+}
+
+func TestGeneratePackageExtensionQuery(t *testing.T) {
+	tt := []struct {
+		version  Version
+		expected []string
+		absent   []string
+	}{
+		{
+			version:  Version{4, 3},
+			expected: []string{"EXT_foo bool", `supportedExtensions["GL_EXT_foo"]`, "GetStringi(EXTENSIONS", "} else if gpGetString != nil {", "GetString(EXTENSIONS)"},
+			absent:   []string{"len(supportedExtensions) == 0"},
+		},
+		{
+			version:  Version{1, 0},
+			expected: []string{"GetString(EXTENSIONS)"},
+			absent:   []string{"GetStringi("},
+		},
+	}
+
+	for _, tc := range tt {
+		pkg := newTestPackage(t, &PackageSpec{API: "gl", Version: tc.version})
+		files := generatePackage(t, pkg)
+		src := files["extensions.go"]
+		if src == nil {
+			t.Fatalf("extensions.go not generated for version %v", tc.version)
+		}
+		for _, content := range tc.expected {
+			if !bytes.Contains(bytes.Join(bytes.Fields(src), []byte(" ")), []byte(content)) {
+				t.Errorf("version %v: extensions.go does not contain %q", tc.version, content)
+			}
+		}
+		for _, content := range tc.absent {
+			if bytes.Contains(src, []byte(content)) {
+				t.Errorf("version %v: extensions.go contains %q", tc.version, content)
+			}
+		}
+		if !bytes.Contains(files["package.go"], []byte("initExtensions()")) {
+			t.Errorf("version %v: Init does not query extensions", tc.version)
+		}
+	}
+}
+
+func TestGeneratePackageVersionQuery(t *testing.T) {
+	tt := []struct {
+		pkgSpec  *PackageSpec
+		expected map[string][]string
+		absent   map[string][]string
+	}{
+		{
+			pkgSpec: &PackageSpec{API: "gl", Version: Version{4, 3}, Profile: "core", StrictVersion: true},
+			expected: map[string][]string{
+				"version.go": {"Major: 4,", `Profile: "core"`, "GetIntegerv(MAJOR_VERSION", "GetIntegerv(CONTEXT_PROFILE_MASK"},
+				"package.go": {"initVersion()", "checkVersion()"},
+			},
+		},
+		{
+			pkgSpec: &PackageSpec{API: "gl", Version: Version{1, 0}},
+			expected: map[string][]string{
+				"version.go": {"Major: 1,", "GetString(VERSION)"},
+				"package.go": {"initVersion()"},
+			},
+			absent: map[string][]string{
+				"version.go": {"GetIntegerv("},
+				"package.go": {"checkVersion()"},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		pkg := newTestPackage(t, tc.pkgSpec)
+		files := generatePackage(t, pkg)
+		for name, contents := range tc.expected {
+			src := bytes.Join(bytes.Fields(files[name]), []byte(" "))
+			for _, content := range contents {
+				if !bytes.Contains(src, []byte(content)) {
+					t.Errorf("version %v: %s does not contain %q", tc.pkgSpec.Version, name, content)
+				}
+			}
+		}
+		for name, contents := range tc.absent {
+			for _, content := range contents {
+				if bytes.Contains(files[name], []byte(content)) {
+					t.Errorf("version %v: %s contains %q", tc.pkgSpec.Version, name, content)
+				}
+			}
+		}
+	}
+}
+
+func TestGeneratePackageVersionRange(t *testing.T) {
+	pkg := newTestPackage(t, &PackageSpec{API: "gl", Version: Version{4, 3}, MinVersion: Version{1, 0}})
+	for name, required := range map[string]bool{
+		"glBindTexture":          true,
+		"glGetString":            true,
+		"glDebugMessageCallback": false,
+		"glGetStringi":           false,
+		"glFooEXT":               false,
+	} {
+		if fn := pkg.Functions[name]; fn == nil || fn.Required != required {
+			t.Errorf("%s required = %v, want %v", name, fn != nil && fn.Required, required)
+		}
+	}
+	if groups := pkg.OptionalVersions(); len(groups) != 1 || groups[0].Label() != "gl 4.3" {
+		t.Errorf("OptionalVersions() = %v, want gl 4.3", groups)
+	}
+
+	files := generatePackage(t, pkg)
+	src := bytes.Join(bytes.Fields(files["package.go"]), []byte(" "))
+	for _, expected := range []string{
+		"var RequiredVersion = FeatureVersion{1, 0}",
+		"var OptionalVersions = []FeatureVersion{ {4, 3}, }",
+		`var optionalVersionGroups = []string{ "gl 4.3", }`,
+		"func VersionSupported(major, minor int) bool",
+		"if p.group == optionalVersionGroups[i] && !p.available() {",
+	} {
+		if !bytes.Contains(src, []byte(expected)) {
+			t.Errorf("package.go does not contain %q", expected)
+		}
+	}
+	if !bytes.Contains(bytes.Join(bytes.Fields(files["version.go"]), []byte(" ")), []byte("Major: 1, Minor: 0,")) {
+		t.Errorf("PackageVersion is not the minimum version of the range")
+	}
+}
+
+func TestGeneratePackageEmptyVersionRange(t *testing.T) {
+	// Restricting the package to symbols of 1.0 leaves no optional versions
+	pkg := newTestPackage(t, &PackageSpec{API: "gl", Version: Version{4, 3}, MinVersion: Version{1, 0}})
+	pkg.Filter(map[string]bool{"GL_ONE": true}, map[string]bool{"glBindTexture": true})
+	if groups := pkg.OptionalVersions(); len(groups) != 0 {
+		t.Fatalf("OptionalVersions() = %v, want none", groups)
+	}
+
+	src := bytes.Join(bytes.Fields(generatePackage(t, pkg)["package.go"]), []byte(" "))
+	for _, expected := range []string{
+		"var OptionalVersions = []FeatureVersion{}",
+		"if len(OptionalVersions) > 0 { last = OptionalVersions[len(OptionalVersions)-1] }",
+	} {
+		if !bytes.Contains(src, []byte(expected)) {
+			t.Errorf("package.go does not contain %q", expected)
+		}
+	}
+}
+
+func TestToCombinedPackage(t *testing.T) {
+	spec := mustParseSpecification(t, packageTestRegistry)
+	pkgSpecs := []*PackageSpec{
+		{API: "gl", Version: Version{4, 3}, TmplDir: "tmpl"},
+		{API: "gles2", Version: Version{2, 0}, TmplDir: "tmpl"},
+	}
+
+	tt := []struct {
+		union     bool
+		functions map[string][2]uint32 // Provided and required API masks
+		enums     []string
+		absent    []string
+	}{
+		{
+			union: true,
+			functions: map[string][2]uint32{
+				"glBindTexture":          {0x3, 0x3},
+				"glDebugMessageCallback": {0x1, 0x1},
+				"glFooEXT":               {0x3, 0x2},
+			},
+			enums: []string{"GL_ONE", "GL_MAJOR_VERSION", "GL_FOO_BIT_EXT"},
+		},
+		{
+			union: false,
+			functions: map[string][2]uint32{
+				"glBindTexture": {0x3, 0x3},
+				"glGetString":   {0x3, 0x3},
+				"glFooEXT":      {0x3, 0x2},
+			},
+			enums:  []string{"GL_ONE", "GL_VERSION"},
+			absent: []string{"glDebugMessageCallback", "GL_MAJOR_VERSION", "GL_FOO_BIT_EXT"},
+		},
+	}
+	for _, tc := range tt {
+		pkg := spec.ToCombinedPackage(pkgSpecs, tc.union)
+		if pkg.API != "gl" || len(pkg.Combined) != 2 || pkg.Combined[1].API != "gles2" {
+			t.Fatalf("union %v: package combines %v", tc.union, pkg.Combined)
+		}
+		for name, masks := range tc.functions {
+			fn, ok := pkg.Functions[name]
+			if !ok {
+				t.Errorf("union %v: missing function %s", tc.union, name)
+				continue
+			}
+			if provided, required := fn.ProvidedAPIs(), fn.RequiredAPIs(); provided != masks[0] || required != masks[1] {
+				t.Errorf("union %v: %s provided by %#x and required by %#x, want %#x and %#x", tc.union, name, provided, required, masks[0], masks[1])
+			}
+		}
+		for _, name := range tc.enums {
+			if _, ok := pkg.Enums[name]; !ok {
+				t.Errorf("union %v: missing enum %s", tc.union, name)
+			}
+		}
+		for _, name := range tc.absent {
+			_, isFn := pkg.Functions[name]
+			_, isEnum := pkg.Enums[name]
+			if isFn || isEnum {
+				t.Errorf("union %v: unexpected symbol %s", tc.union, name)
+			}
+		}
+	}
+
+	pkg := spec.ToCombinedPackage(pkgSpecs, true)
+	files := generatePackage(t, pkg)
+	for name, contents := range map[string][]string{
+		"package.go": {
+			`{name: "glFooEXT", ptr: (*unsafe.Pointer)(unsafe.Pointer(&gpFooEXT)), apis: 3, requiredBy: 2, group: "GL_EXT_foo"}`,
+			"// Provided by gl 4.3, gles2 2.0.",
+			"// Provided by GL_EXT_foo (gl), gles2 2.0.",
+			"apis := contextAPIs()",
+		},
+		"version.go": {
+			`{ES: false, SC: false, Major: 4, Minor: 3, Profile: ""},`,
+			`{ES: true, SC: false, Major: 2, Minor: 0, Profile: ""},`,
+		},
+	} {
+		for _, content := range contents {
+			if !bytes.Contains(files[name], []byte(content)) {
+				t.Errorf("%s does not contain %q", name, content)
+			}
+		}
+	}
+	if bytes.Contains(files["version.go"], []byte("var PackageVersion =")) {
+		t.Errorf("version.go of a combined package declares PackageVersion")
+	}
+}
+
+func TestGeneratePackageAliasFallback(t *testing.T) {
+	pkg := newTestPackage(t, &PackageSpec{API: "gl", Version: Version{4, 3}, AliasFallback: true})
+	if fallbacks := pkg.Functions["glBindTexture"].Fallbacks; !reflect.DeepEqual(fallbacks, []string{"glBindTextureEXT"}) {
+		t.Errorf("glBindTexture falls back to %v", fallbacks)
+	}
+	if fallbacks := pkg.Functions["glBindTextureEXT"].Fallbacks; len(fallbacks) != 0 {
+		t.Errorf("glBindTextureEXT falls back to %v", fallbacks)
+	}
+	src := generatePackage(t, pkg)["package.go"]
+	for _, expected := range []string{
+		`group: "gl 1.0", aliases: []string{"glBindTextureEXT"}}`,
+		"*p.ptr = bindProc(getProcAddr, i)",
+		"func BoundName(name string) string",
+	} {
+		if !bytes.Contains(src, []byte(expected)) {
+			t.Errorf("package.go does not contain %q", expected)
+		}
+	}
+
+	// Aliases are only bound on request
+	pkg = newTestPackage(t, &PackageSpec{API: "gl", Version: Version{4, 3}})
+	if fallbacks := pkg.Functions["glBindTexture"].Fallbacks; len(fallbacks) != 0 {
+		t.Errorf("glBindTexture falls back to %v without AliasFallback", fallbacks)
+	}
+}
+
+func TestMergeFallbacks(t *testing.T) {
+	got := mergeFallbacks([]string{"glFooNV", "glFooEXT"}, []string{"glFooOES", "glFooARB", "glFooNV"})
+	want := []string{"glFooARB", "glFooOES", "glFooEXT", "glFooNV"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeFallbacks() = %v, want %v", got, want)
+	}
+}
+
+func TestGeneratePackageRemovals(t *testing.T) {
+	tt := []struct {
+		pkgSpec *PackageSpec
+		removed bool // Whether the package keeps the removed symbols
+	}{
+		{&PackageSpec{API: "gl", Version: Version{4, 3}, Profile: "core"}, false},
+		{&PackageSpec{API: "gl", Version: Version{4, 3}, Profile: "compatibility"}, true},
+		{&PackageSpec{API: "gl", Version: Version{-1, -1}}, true},
+		{&PackageSpec{API: "gl", Version: Version{-1, -1}, Profile: "core"}, true},
+	}
+	for _, tc := range tt {
+		pkg := newTestPackage(t, tc.pkgSpec)
+		fn, hasFn := pkg.Functions["glDeleteTextures"]
+		enum, hasEnum := pkg.Enums["GL_ZERO"]
+		if hasFn != tc.removed || hasEnum != tc.removed {
+			t.Errorf("%v %s: package has glDeleteTextures %v and GL_ZERO %v", tc.pkgSpec.Version, tc.pkgSpec.Profile, hasFn, hasEnum)
+			continue
+		}
+		if !tc.removed {
+			continue
+		}
+		want := &Removal{Version: Version{4, 3}, Profile: "core"}
+		if !reflect.DeepEqual(fn.Removed, want) || !reflect.DeepEqual(enum.Removed, want) {
+			t.Errorf("%v %s: removals %v and %v, want %v", tc.pkgSpec.Version, tc.pkgSpec.Profile, fn.Removed, enum.Removed, want)
+		}
+		if pkg.Functions["glBindTexture"].Removed != nil {
+			t.Errorf("%v %s: glBindTexture is removed", tc.pkgSpec.Version, tc.pkgSpec.Profile)
+		}
+	}
+
+	pkg := newTestPackage(t, &PackageSpec{API: "gl", Version: Version{4, 3}, Profile: "compatibility"})
+	pkg.Functions["glDeleteTextures"].Doc = "delete named textures"
+	src := generatePackage(t, pkg)["package.go"]
+	for _, expected := range []*regexp.Regexp{
+		regexp.MustCompile(`// delete named textures\n//\n// Deprecated: removed from core profile in 4\.3\.\nfunc DeleteTextures\(`),
+		regexp.MustCompile(`// Deprecated: removed from core profile in 4\.3\.\n\s*ZERO\s*= 0\n`),
+	} {
+		if !expected.Match(src) {
+			t.Errorf("package.go does not match %s", expected)
+		}
+	}
+}
+
+func TestRemovalRestored(t *testing.T) {
+	spec := mustParseSpecification(t, `<registry>
+  <types>
+    <type>typedef unsigned int <name>GLenum</name>;</type>
+  </types>
+  <enums>
+    <enum name="GL_STACK_OVERFLOW" value="0x0503"/>
+    <enum name="GL_ACCUM" value="0x0100"/>
+  </enums>
+  <commands>
+    <command>
+      <proto>void <name>glGetPointerv</name></proto>
+      <param><ptype>GLenum</ptype> <name>pname</name></param>
+    </command>
+    <command>
+      <proto>void <name>glBegin</name></proto>
+      <param><ptype>GLenum</ptype> <name>mode</name></param>
+    </command>
+  </commands>
+  <feature api="gl" name="GL_VERSION_1_1" number="1.1">
+    <require>
+      <command name="glGetPointerv"/>
+      <command name="glBegin"/>
+      <enum name="GL_STACK_OVERFLOW"/>
+      <enum name="GL_ACCUM"/>
+    </require>
+  </feature>
+  <feature api="gl" name="GL_VERSION_3_2" number="3.2">
+    <remove profile="core">
+      <command name="glGetPointerv"/>
+      <command name="glBegin"/>
+      <enum name="GL_STACK_OVERFLOW"/>
+      <enum name="GL_ACCUM"/>
+    </remove>
+  </feature>
+  <feature api="gl" name="GL_VERSION_4_3" number="4.3">
+    <require>
+      <command name="glGetPointerv"/>
+      <enum name="GL_STACK_OVERFLOW"/>
+    </require>
+    <require profile="compatibility">
+      <command name="glBegin"/>
+      <enum name="GL_ACCUM"/>
+    </require>
+  </feature>
+</registry>`)
+
+	for _, pkgSpec := range []*PackageSpec{
+		{API: "gl", Version: Version{4, 3}, Profile: "compatibility"},
+		{API: "gl", Version: Version{-1, -1}},
+	} {
+		pkg := spec.ToPackage(pkgSpec)
+		if removed := pkg.Functions["glGetPointerv"].Removed; removed != nil {
+			t.Errorf("%v %s: glGetPointerv restored by 4.3 is removed in %v", pkgSpec.Version, pkgSpec.Profile, removed.Version)
+		}
+		if removed := pkg.Enums["GL_STACK_OVERFLOW"].Removed; removed != nil {
+			t.Errorf("%v %s: GL_STACK_OVERFLOW restored by 4.3 is removed in %v", pkgSpec.Version, pkgSpec.Profile, removed.Version)
+		}
+		// The compatibility profile re-adding symbols leaves them removed from core
+		if pkg.Functions["glBegin"].Removed == nil || pkg.Enums["GL_ACCUM"].Removed == nil {
+			t.Errorf("%v %s: glBegin or GL_ACCUM is not removed", pkgSpec.Version, pkgSpec.Profile)
+		}
+	}
+}
+
+func TestGeneratePackageLazyInit(t *testing.T) {
+	pkg := newTestPackage(t, &PackageSpec{API: "gl", Version: Version{4, 3}, LazyInit: true})
+	src := string(generatePackage(t, pkg)["package.go"])
+
+	for _, expected := range []*regexp.Regexp{
+		regexp.MustCompile(`gpBindTexture := \(C\.GPBINDTEXTURE\)\(lazyProc\(unsafe\.Pointer\(&gpBindTexture\), "glBindTexture"\)\)`),
+		regexp.MustCompile(`\{name: "glGetString", .*, eager: true\}`),
+		regexp.MustCompile(`procAddrFunc = getProcAddr`),
+	} {
+		if !expected.MatchString(src) {
+			t.Errorf("package.go does not match %s", expected)
+		}
+	}
+	if regexp.MustCompile(`\{name: "glBindTexture", .*eager`).MatchString(src) {
+		t.Errorf("glBindTexture is loaded by Init")
+	}
+}
+
+func TestEnumValues(t *testing.T) {
+	pkg := newTestPackage(t, &PackageSpec{API: "gl", Version: Version{4, 3}})
+	pkg.Enums["GL_TIMEOUT_IGNORED"] = &Enum{Name: "GL_TIMEOUT_IGNORED", GoName: "TIMEOUT_IGNORED", Value: "0xFFFFFFFFFFFFFFFF"}
+
+	values := make(map[uint32][]string)
+	for i, value := range pkg.EnumValues() {
+		if i > 0 && value.Value <= pkg.EnumValues()[i-1].Value {
+			t.Errorf("enum values not ordered at %#x", value.Value)
+		}
+		values[value.Value] = value.Names
+	}
+
+	expected := map[uint32][]string{
+		0x0000: {"NO_ERROR", "ZERO"},
+		0x0001: {"CONTEXT_CORE_PROFILE_BIT", "ONE", "FOO_BIT_EXT"},
+		0x0DE1: {"TEXTURE_2D"},
+	}
+	for value, names := range expected {
+		if !reflect.DeepEqual(values[value], names) {
+			t.Errorf("names of %#x: expected %v, got %v", value, names, values[value])
+		}
+	}
+	for value, names := range values {
+		for _, name := range names {
+			if name == "TIMEOUT_IGNORED" {
+				t.Errorf("64-bit enum listed with value %#x", value)
+			}
+		}
+	}
+}
+
+func TestGeneratePackageEnumNames(t *testing.T) {
+	pkg := newTestPackage(t, &PackageSpec{API: "gl", Version: Version{4, 3}})
+	files := generatePackage(t, pkg)
+
+	src := files["enumnames.go"]
+	if !bytes.Contains(src, []byte("//go:build !glow_no_enum_names\n")) {
+		t.Errorf("enum name table is not guarded by a build tag:\n%s", src)
+	}
+	if !regexp.MustCompile(`0x0001: +\{"CONTEXT_CORE_PROFILE_BIT", "ONE", "FOO_BIT_EXT"\},`).Match(src) {
+		t.Errorf("enum name table does not list the enums sharing 0x0001:\n%s", src)
+	}
+	if !bytes.Contains(files["package.go"], []byte("func EnumName(value uint32) []string")) {
+		t.Errorf("package.go does not declare EnumName")
+	}
+}
+
+func TestGeneratePackageErrors(t *testing.T) {
+	pkg := newTestPackage(t, &PackageSpec{API: "gl", Version: Version{1, 0}})
+	files := generatePackage(t, pkg)
+	src := files["errors.go"]
+	if src == nil {
+		t.Fatal("errors.go not generated")
+	}
+
+	for _, expected := range []*regexp.Regexp{
+		regexp.MustCompile(`ErrInvalidEnum +Error = INVALID_ENUM`),
+		regexp.MustCompile(`ErrInvalidOperation +Error = INVALID_OPERATION`),
+		regexp.MustCompile(`func \(e Error\) String\(\) string`),
+		regexp.MustCompile(`case NO_ERROR:\n\s+return "NO_ERROR"`),
+		regexp.MustCompile(`case ErrInvalidOperation:\n\s+return "INVALID_OPERATION"`),
+		regexp.MustCompile(`func CheckError\(\) error`),
+		regexp.MustCompile(`func \(errs Errors\) Is\(target error\) bool`),
+	} {
+		if !expected.Match(src) {
+			t.Errorf("errors.go does not match %s", expected)
+		}
+	}
+	// Error codes missing from the package have no errors
+	if bytes.Contains(src, []byte("ErrInvalidValue")) {
+		t.Errorf("errors.go declares ErrInvalidValue")
+	}
+	// errors.Join requires Go 1.20
+	if bytes.Contains(src, []byte("errors.Join")) {
+		t.Errorf("errors.go uses errors.Join")
+	}
+	if !regexp.MustCompile(`func GetError\(\) Error {\n\s+ret := C.glowGetError\(gpGetError\)\n\s+return \(Error\)\(ret\)`).Match(files["package.go"]) {
+		t.Errorf("GetError does not return an Error")
+	}
+
+	// Without the error type GetError returns the bare error code
+	pkg.Filter(map[string]bool{"GL_INVALID_ENUM": true}, nil)
+	files = generatePackage(t, pkg)
+	if files["errors.go"] != nil {
+		t.Errorf("errors.go generated without GL_NO_ERROR")
+	}
+	if !bytes.Contains(files["package.go"], []byte("func GetError() uint32 {")) {
+		t.Errorf("GetError does not return a uint32 without the Error type")
+	}
+}
+
+func TestGeneratePackageDebugCallback(t *testing.T) {
+	pkg := newTestPackage(t, &PackageSpec{API: "gl", Version: Version{4, 3}})
+	files := generatePackage(t, pkg)
+
+	expected := map[string][]string{
+		"package.go": {
+			"func DebugMessageCallback(callback DebugProc, userParam interface{}) {",
+			"callbackProc = C.glowCallbackProc_GLDEBUGPROC()",
+			"userParamHandle = newCallbackHandle(callback, userParam)",
+			"C.glowCallbackUserParam(C.uintptr_t(userParamHandle))",
+			"releaseCallback(previous)",
+			// The headers of the registry need not declare uintptr_t
+			"// #include <stdint.h>",
+		},
+		"callbacks.go": {
+			"cgo.NewHandle(",
+			"type DebugProc func(source uint32, xtype uint32, id uint32, severity uint32, length int32, message string, userParam interface{})",
+			"//export glowCallback_GLDEBUGPROC_",
+		},
+		"debug.go": {
+			`if IsAvailable("glGetPointerv") {`,
+		},
+	}
+	for name, contents := range expected {
+		for _, content := range contents {
+			if !bytes.Contains(files[name], []byte(content)) {
+				t.Errorf("%s does not contain %q", name, content)
+			}
+		}
+	}
+	if bytes.Contains(files["package.go"], []byte("userDebugCallback")) {
+		t.Errorf("package.go stores the callback in a package variable")
+	}
+}
+
+func TestCallbackParamTypes(t *testing.T) {
+	pkg := newTestPackage(t, &PackageSpec{API: "gl", Version: Version{4, 3}})
+	index := make(map[string]int)
+	for i, typedef := range pkg.Typedefs {
+		index[typedef.Name] = i
+	}
+	// GLDEBUGPROC takes a GLchar message, which no function of the package takes
+	callback, ok := index["GLDEBUGPROC"]
+	if !ok {
+		t.Fatal("GLDEBUGPROC not selected")
+	}
+	if param, ok := index["GLchar"]; !ok || param > callback {
+		t.Errorf("GLchar not declared before GLDEBUGPROC")
+	}
+}
+
+func TestGeneratePackageHalf(t *testing.T) {
+	pkg := newTestPackage(t, &PackageSpec{API: "gl", Version: Version{4, 3}})
+	files := generatePackage(t, pkg)
+
+	expected := map[string][]string{
+		"package.go": {
+			"func VertexAttrib1hNV(index uint32, x Half) {",
+			"(C.GLhalfNV)(x)",
+		},
+		"half.go": {
+			"type Half uint16",
+			"func NewHalf(f float32) Half {",
+			"func (h Half) Float32() float32 {",
+		},
+	}
+	for name, contents := range expected {
+		for _, content := range contents {
+			if !bytes.Contains(files[name], []byte(content)) {
+				t.Errorf("%s does not contain %q", name, content)
+			}
+		}
+	}
+
+	pkg = newTestPackage(t, &PackageSpec{API: "gl", Version: Version{4, 3}, RemExtRegexp: regexp.MustCompile("NV")})
+	if pkg.HasHalfType() {
+		t.Errorf("package without half floats has Half type")
+	}
+}
+
+// buildTestFunctions and buildTestEnums restrict the packages generated from
+// the bundled registry by TestGeneratedPackagesBuild, so that building them
+// stays quick.
+var (
+	buildTestFunctions = lookupMap([]string{
+		"glBindTexture", "glGetError", "glGetIntegerv", "glGetString", "glGetStringi",
+		"glGetPointerv", "glDebugMessageCallback", "glVertexAttrib1hNV",
+		"glDrawArraysInstanced", "glDrawArraysInstancedARB", "glDrawArraysInstancedEXT",
+	})
+	buildTestEnums = lookupMap([]string{
+		"GL_TEXTURE_2D", "GL_VERSION", "GL_EXTENSIONS", "GL_NUM_EXTENSIONS",
+		"GL_MAJOR_VERSION", "GL_MINOR_VERSION", "GL_CONTEXT_PROFILE_MASK",
+		"GL_CONTEXT_CORE_PROFILE_BIT", "GL_CONTEXT_COMPATIBILITY_PROFILE_BIT",
+		"GL_NO_ERROR", "GL_INVALID_ENUM", "GL_INVALID_VALUE", "GL_INVALID_OPERATION",
+	})
+)
+
+// TestGeneratedPackagesBuild builds and vets packages generated from the
+// bundled registry in the modes changing the structure of the package.
+func TestGeneratedPackagesBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping build of generated packages in short mode")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	if out, err := exec.Command(goTool, "env", "CGO_ENABLED").Output(); err != nil || strings.TrimSpace(string(out)) != "1" {
+		t.Skip("cgo is not enabled")
+	}
+
+	registry, err := readSpecFile(filepath.Join("xml", "spec", "gl.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := NewSpecification(*registry, xmlOverloads{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPackage := func(pkgSpec *PackageSpec) *Package {
+		pkgSpec.TmplDir = "tmpl"
+		pkg := spec.ToPackage(pkgSpec)
+		pkg.Filter(buildTestEnums, buildTestFunctions)
+		return pkg
+	}
+	newCombinedPackage := func(union bool) *Package {
+		pkg := spec.ToCombinedPackage([]*PackageSpec{
+			{API: "gl", Version: Version{4, 3}, Profile: "core", TmplDir: "tmpl"},
+			{API: "gles2", Version: Version{3, 2}, TmplDir: "tmpl"},
+		}, union)
+		pkg.Filter(buildTestEnums, buildTestFunctions)
+		return pkg
+	}
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	for name, pkg := range map[string]*Package{
+		"eager":    newPackage(&PackageSpec{API: "gl", Version: Version{4, 3}, Profile: "core"}),
+		"lazy":     newPackage(&PackageSpec{API: "gl", Version: Version{4, 3}, Profile: "core", LazyInit: true}),
+		"split":    newPackage(&PackageSpec{API: "gl", Version: Version{4, 3}, Profile: "core", SplitFiles: true}),
+		"exttags":  newPackage(&PackageSpec{API: "gl", Version: Version{4, 3}, Profile: "core", ExtensionTags: true}),
+		"versions": newPackage(&PackageSpec{API: "gl", Version: Version{4, 3}, Profile: "core", MinVersion: Version{3, 0}}),
+		"aliases":  newPackage(&PackageSpec{API: "gl", Version: Version{4, 3}, Profile: "core", AliasFallback: true}),
+		"gles2":    newPackage(&PackageSpec{API: "gles2", Version: Version{3, 2}}),
+		// Combined packages declare the functions of either or both APIs
+		"union":        newCombinedPackage(true),
+		"intersection": newCombinedPackage(false),
+		// The fixture lacks khrplatform.h and relies on the types it declares
+		"fixture": newTestPackage(t, &PackageSpec{API: "gl", Version: Version{4, 3}}),
+		"opencl":  newRegistryPackage(t, filepath.Join("testdata", "cl.xml"), &PackageSpec{API: "opencl", Version: Version{3, 0}}),
+		"vulkan":  newRegistryPackage(t, filepath.Join("testdata", "vk.xml"), &PackageSpec{API: "vulkan", Version: Version{1, 3}}),
+		"webgl":   newWebGLTestPackage(t),
+		"egl":     newWindowSystemTestPackage(t, "egl", Version{1, 5}),
+		"glx":     newWindowSystemTestPackage(t, "glx", Version{1, 4}),
+		"wgl":     newWindowSystemTestPackage(t, "wgl", Version{1, 0}),
+		// The headless context relies on EGL extensions the package loads
+		"headless": newRegistryPackage(t, filepath.Join("xml", "spec", "egl.xml"), &PackageSpec{API: "egl", Version: Version{1, 5}, Headless: true}),
+	} {
+		pkgDir := filepath.Join(dir, name)
+		if err := pkg.GeneratePackage(pkgDir); err != nil {
+			t.Fatalf("GeneratePackage(%s) failed: %v", name, err)
+		}
+		if err := copyIncludes(filepath.Join("xml", "include"), pkgDir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module glowtest\n\ngo 1.17\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The conversions of uintptr to unsafe.Pointer are deliberate
+	for _, step := range []struct {
+		env  []string
+		args []string
+	}{
+		{nil, []string{"build", "./..."}},
+		{nil, []string{"vet", "-unsafeptr=false", "./..."}},
+		// Packages of the webgl backend only build for js/wasm
+		{[]string{"GOOS=js", "GOARCH=wasm"}, []string{"build", "./webgl"}},
+		{[]string{"GOOS=js", "GOARCH=wasm"}, []string{"vet", "-unsafeptr=false", "./webgl"}},
+	} {
+		cmd := exec.Command(goTool, step.args...)
+		cmd.Dir = dir
+		cmd.Env = append(append(os.Environ(), "GOFLAGS=", "GOWORK=off", "GOTOOLCHAIN=local"), step.env...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			command := strings.Join(append(append(step.env, "go"), step.args...), " ")
+			t.Errorf("%s failed: %v\n%s", command, err, out)
+		}
+	}
+}
+
+// TestGLSC2Symbols checks the symbols of an OpenGL SC 2.0 package generated
+// from the bundled registry against the GL_SC_VERSION_2_0 feature.
+func TestGLSC2Symbols(t *testing.T) {
+	registry, err := readSpecFile(filepath.Join("xml", "spec", "gl.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := NewSpecification(*registry, xmlOverloads{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgSpec := &PackageSpec{
+		API:          "glsc2",
+		Version:      Version{2, 0},
+		TmplDir:      "tmpl",
+		RemExtRegexp: regexp.MustCompile("."),
+	}
+	if !spec.HasPackage(pkgSpec) {
+		t.Fatal("registry cannot generate glsc2 package")
+	}
+	pkg := spec.ToPackage(pkgSpec)
+
+	// OpenGL SC 2.0 is a single feature without removals
+	functions := make(map[string]bool)
+	enums := make(map[string]bool)
+	for _, feature := range registry.Features {
+		if feature.API != "glsc2" || feature.Number != "2.0" {
+			continue
+		}
+		for _, require := range feature.Requires {
+			for _, command := range require.Commands {
+				functions[command.Name] = true
+			}
+			for _, enum := range require.Enums {
+				enums[enum.Name] = true
+			}
+		}
+	}
+	if len(functions) == 0 || len(enums) == 0 {
+		t.Fatal("registry lacks the glsc2 2.0 feature")
+	}
+
+	for name := range functions {
+		if _, ok := pkg.Functions[name]; !ok {
+			t.Errorf("package lacks function %s", name)
+		}
+	}
+	for name := range pkg.Functions {
+		if !functions[name] {
+			t.Errorf("package has function %s outside of the feature", name)
+		}
+	}
+	for name := range enums {
+		if _, ok := pkg.Enums[name]; !ok {
+			t.Errorf("package lacks enum %s", name)
+		}
+	}
+	for name := range pkg.Enums {
+		if !enums[name] {
+			t.Errorf("package has enum %s outside of the feature", name)
+		}
+	}
+}
+
+func TestGenerateOpenCLPackage(t *testing.T) {
+	registry, err := readSpecFile(filepath.Join("testdata", "cl.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := NewSpecification(*registry, xmlOverloads{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgSpec := &PackageSpec{API: "opencl", Version: Version{3, 0}, TmplDir: "tmpl"}
+	if !spec.HasPackage(pkgSpec) {
+		t.Fatal("registry cannot generate opencl package")
+	}
+	pkg := spec.ToPackage(pkgSpec)
+	files := generatePackage(t, pkg)
+
+	expected := map[string][]string{
+		"package.go": {
+			"// Package opencl implements Go bindings to OpenCL.",
+			"typedef int32_t cl_int;",
+			"typedef struct _cl_image_format {",
+			"func GetDeviceIDs(platform unsafe.Pointer, device_type uint64, num_entries uint32, devices unsafe.Pointer, num_devices *uint32) int32 {",
+			"func CreateContext(properties *int, num_devices uint32, devices unsafe.Pointer, pfn_notify CreateContextNotify, user_data interface{}, errcode_ret *int32) unsafe.Pointer {",
+		},
+		"callbacks.go": {
+			"type CreateContextNotify func(errinfo string, private_info unsafe.Pointer, cb uint, user_data interface{})",
+		},
+		"procaddr.go": {
+			"clGetExtensionFunctionAddressForPlatform",
+			"func PlatformProcAddrFunc(platform unsafe.Pointer) func(name string) unsafe.Pointer {",
+		},
+	}
+	for name, contents := range expected {
+		for _, content := range contents {
+			if !bytes.Contains(files[name], []byte(content)) {
+				t.Errorf("%s does not contain %q", name, content)
+			}
+		}
+	}
+
+	for _, enum := range []string{`DEVICE_TYPE_GPU\s+= 0x4\n`, `INVALID_VALUE\s+= -30\n`} {
+		if !regexp.MustCompile(enum).Match(files["package.go"]) {
+			t.Errorf("package.go does not match %q", enum)
+		}
+	}
+
+	pkgSpec.Version = Version{1, 1}
+	pkg = spec.ToPackage(pkgSpec)
+	if _, ok := pkg.Functions["clSetEventCallback"]; !ok {
+		t.Errorf("OpenCL 1.1 package lacks clSetEventCallback")
+	}
+	if _, ok := pkg.Functions["clSetDefaultDeviceCommandQueue"]; ok {
+		t.Errorf("OpenCL 1.1 package has OpenCL 2.1 function clSetDefaultDeviceCommandQueue")
+	}
+}
+
+func TestGenerateVulkanPackage(t *testing.T) {
+	registry, err := readSpecFile(filepath.Join("testdata", "vk.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := NewSpecification(*registry, xmlOverloads{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgSpec := &PackageSpec{API: "vulkan", Version: Version{1, 3}, TmplDir: "tmpl"}
+	if !spec.HasPackage(pkgSpec) {
+		t.Fatal("registry cannot generate vulkan package")
+	}
+	pkg := spec.ToPackage(pkgSpec)
+	files := generatePackage(t, pkg)
+
+	expected := map[string][]string{
+		"package.go": {
+			"// Package vulkan implements Go bindings to Vulkan.",
+			"func CreateInstance(pCreateInfo *InstanceCreateInfo, pAllocator *AllocationCallbacks, pInstance *unsafe.Pointer) int32 {",
+			"func CreateBuffer(device unsafe.Pointer, pCreateInfo *BufferCreateInfo, pAllocator *AllocationCallbacks, pBuffer *uint64) int32 {",
+			"func GetPhysicalDeviceProperties2KHR(physicalDevice unsafe.Pointer, pProperties *PhysicalDeviceProperties2) {",
+			`{name: "vkCreateInstance", ptr: (*unsafe.Pointer)(unsafe.Pointer(&gpCreateInstance)), required: true, group: "vulkan 1.0", dispatch: dispatchGlobal},`,
+			`dispatch: dispatchInstance},`,
+			`{name: "vkQueueWaitIdle", ptr: (*unsafe.Pointer)(unsafe.Pointer(&gpQueueWaitIdle)), required: true, group: "vulkan 1.0", dispatch: dispatchDevice},`,
+		},
+		"structs.go": {
+			"//     struct _VkBaseOutStructure* pNext;",
+			"\tPNext *BaseOutStructure\n",
+			"\tDeviceName        [256]uint8\n",
+			"\tMatrix [3][4]float32\n",
+			"\tInstanceCustomIndexAndMask                     uint32 // instanceCustomIndex:24, mask:8\n",
+			"\tdata [unsafe.Sizeof(C.VkClearColorValue{})]byte\n",
+			"func (u *ClearValue) DepthStencil() *ClearDepthStencilValue {",
+			"type PhysicalDeviceProperties2KHR = PhysicalDeviceProperties2",
+			"_ [unsafe.Sizeof(PhysicalDeviceProperties{}) - unsafe.Sizeof(C.VkPhysicalDeviceProperties{})]byte",
+		},
+		"vulkan.go": {
+			"func InitInstance(instance unsafe.Pointer) error {",
+			"func InitDevice(device unsafe.Pointer) error {",
+			"const PackageAPIVersion = 1<<22 | 3<<12",
+		},
+		"procaddr.go": {
+			`#define GLOW_VULKAN_LIBRARY "libvulkan.so.1"`,
+		},
+	}
+	for name, contents := range expected {
+		for _, content := range contents {
+			if !bytes.Contains(files[name], []byte(content)) {
+				t.Errorf("%s does not contain %q", name, content)
+			}
+		}
+	}
+
+	for _, enum := range []string{
+		`ERROR_SURFACE_LOST_KHR\s+= -1000000000\n`,
+		`ERROR_OUT_OF_DATE_KHR\s+= -1000001004\n`,
+		`IMAGE_LAYOUT_PRESENT_SRC_KHR\s+= 1000001002\n`,
+		`STRUCTURE_TYPE_PHYSICAL_DEVICE_PROPERTIES_2_KHR\s+= 1000059001\n`,
+		`PIPELINE_STAGE_2_RESOLVE_BIT\s+= 0x200000000\n`,
+		`WHOLE_SIZE\s+= 0xFFFFFFFFFFFFFFFF\n`,
+		`LOD_CLAMP_NONE\s+= 1000.0\n`,
+		`KHR_SURFACE_EXTENSION_NAME\s+= "VK_KHR_surface"\n`,
+	} {
+		if !regexp.MustCompile(enum).Match(files["package.go"]) {
+			t.Errorf("package.go does not match %q", enum)
+		}
+	}
+	for _, symbol := range []string{"vkCreateXlibSurfaceKHR", "VkXlibSurfaceCreateInfoKHR", "VK_NV_extension_1", "VKSC_VERSION_1_0"} {
+		if bytes.Contains(files["package.go"], []byte(symbol)) {
+			t.Errorf("package.go contains %s", symbol)
+		}
+	}
+
+	// Requirements depending on a later version are left out
+	pkgSpec.Version = Version{1, 0}
+	pkg = spec.ToPackage(pkgSpec)
+	if _, ok := pkg.Enums["VK_STRUCTURE_TYPE_DEVICE_GROUP_PRESENT_CAPABILITIES_KHR"]; ok {
+		t.Errorf("Vulkan 1.0 package has VK_STRUCTURE_TYPE_DEVICE_GROUP_PRESENT_CAPABILITIES_KHR")
+	}
+	if _, ok := pkg.Functions["vkDestroySwapchainKHR"]; !ok {
+		t.Errorf("Vulkan 1.0 package lacks vkDestroySwapchainKHR")
+	}
+	for _, extension := range pkg.Extensions {
+		if extension.Name == "VK_KHR_acceleration_structure" {
+			t.Errorf("Vulkan 1.0 package has VK_KHR_acceleration_structure, which depends on Vulkan 1.1")
+		}
+	}
+
+	// Window system extensions are left out unless requested
+	pkgSpec.AddExtRegexp = regexp.MustCompile("^VK_KHR_xlib_surface$")
+	pkg = spec.ToPackage(pkgSpec)
+	if _, ok := pkg.Functions["vkCreateXlibSurfaceKHR"]; !ok {
+		t.Errorf("package lacks vkCreateXlibSurfaceKHR requested through addext")
+	}
+}
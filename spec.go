@@ -7,7 +7,10 @@ import (
 	"io"
 	"os"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
 type xmlRegistry struct {
@@ -19,26 +22,37 @@ type xmlRegistry struct {
 }
 
 type xmlType struct {
-	Name     string `xml:"name,attr"`
-	API      string `xml:"api,attr"`
-	Requires string `xml:"requires,attr"`
-	Raw      []byte `xml:",innerxml"`
+	Name      string `xml:"name,attr"`
+	API       string `xml:"api,attr"`
+	Requires  string `xml:"requires,attr"`
+	Bitvalues string `xml:"bitvalues,attr"`
+	Category  string `xml:"category,attr"`
+	Alias     string `xml:"alias,attr"`
+	Raw       []byte `xml:",innerxml"`
 }
 
 type xmlEnumSet struct {
-	Enums []xmlEnum `xml:"enum"`
+	Name     string    `xml:"name,attr"`
+	Type     string    `xml:"type,attr"`
+	Bitwidth int       `xml:"bitwidth,attr"`
+	Enums    []xmlEnum `xml:"enum"`
 }
 
 type xmlEnum struct {
-	Name  string `xml:"name,attr"`
-	Value string `xml:"value,attr"`
-	API   string `xml:"api,attr"`
+	Name   string `xml:"name,attr"`
+	Value  string `xml:"value,attr"`
+	Bitpos string `xml:"bitpos,attr"`
+	Alias  string `xml:"alias,attr"`
+	API    string `xml:"api,attr"`
 }
 
 type xmlCommand struct {
-	Prototype xmlProto   `xml:"proto"`
-	API       string     `xml:"api"`
-	Params    []xmlParam `xml:"param"`
+	Name      string        `xml:"name,attr"`
+	Alias     string        `xml:"alias,attr"`
+	Prototype xmlProto      `xml:"proto"`
+	API       string        `xml:"api,attr"`
+	Params    []xmlParam    `xml:"param"`
+	AliasOf   xmlCommandRef `xml:"alias"` // Equivalent command, e.g., glBindBuffer for glBindBufferARB
 }
 
 type xmlSignature []byte
@@ -53,6 +67,7 @@ type xmlParam struct {
 
 type xmlFeature struct {
 	API      string       `xml:"api,attr"`
+	Name     string       `xml:"name,attr"`
 	Number   string       `xml:"number,attr"`
 	Requires []xmlRequire `xml:"require"`
 	Removes  []xmlRemove  `xml:"remove"`
@@ -61,7 +76,9 @@ type xmlFeature struct {
 type xmlRequire struct {
 	Enums    []xmlEnumRef    `xml:"enum"`
 	Commands []xmlCommandRef `xml:"command"`
+	Types    []xmlTypeRef    `xml:"type"`
 	Profile  string          `xml:"profile,attr"`
+	Depends  string          `xml:"depends,attr"`
 }
 
 type xmlRemove struct {
@@ -70,17 +87,34 @@ type xmlRemove struct {
 	Profile  string          `xml:"profile,attr"`
 }
 
+// An xmlEnumRef refers to an enum. Vulkan also defines enums where they are
+// required, either through their value or through an offset within the
+// values reserved for an extension.
 type xmlEnumRef struct {
-	Name string `xml:"name,attr"`
+	Name      string `xml:"name,attr"`
+	API       string `xml:"api,attr"`
+	Value     string `xml:"value,attr"`
+	Bitpos    string `xml:"bitpos,attr"`
+	Offset    string `xml:"offset,attr"`
+	Extnumber string `xml:"extnumber,attr"`
+	Dir       string `xml:"dir,attr"`
+	Alias     string `xml:"alias,attr"`
 }
 
 type xmlCommandRef struct {
 	Name string `xml:"name,attr"`
 }
 
+type xmlTypeRef struct {
+	Name string `xml:"name,attr"`
+}
+
 type xmlExtension struct {
 	Name      string       `xml:"name,attr"`
+	Number    string       `xml:"number,attr"`
 	Supported string       `xml:"supported,attr"`
+	Platform  string       `xml:"platform,attr"`
+	Depends   string       `xml:"depends,attr"`
 	Requires  []xmlRequire `xml:"require"`
 	Removes   []xmlRemove  `xml:"remove"`
 }
@@ -92,20 +126,29 @@ type specRef struct {
 
 type specTypedef struct {
 	typedef  *Typedef
-	ordinal  int    // Relative declaration order of the typedef
-	requires string // Optional name of the typedef required for this typedef
+	ordinal  int      // Relative declaration order of the typedef
+	requires []string // Names of the typedefs required for this typedef
+}
+
+// A specEnumType lists the enums of an enumerated type such as VkResult.
+type specEnumType struct {
+	enums    []string
+	bitwidth int // Width of the type in bits if it is not 32
 }
 
 type specFunctions map[specRef]*Function
 type specEnums map[specRef]*Enum
 type specTypedefs map[specRef]*specTypedef
+type specEnumTypes map[string]*specEnumType
 
 type specAddRemSet struct {
 	addedCommands   []string
 	addedEnums      []string
+	addedTypes      []string
 	removedCommands []string
 	removedEnums    []string
 	profile         string
+	depends         string // Features and extensions the set depends on, if any
 }
 
 // A Specification is a parsed version of an XML registry.
@@ -113,6 +156,7 @@ type Specification struct {
 	Functions  specFunctions
 	Enums      specEnums
 	Typedefs   specTypedefs
+	EnumTypes  specEnumTypes
 	Features   []SpecificationFeature
 	Extensions []SpecificationExtension
 }
@@ -121,6 +165,7 @@ type Specification struct {
 // removed in the context of a particular API and version.
 type SpecificationFeature struct {
 	API     string
+	Name    string // Name other features and extensions refer to it by, e.g., "VK_VERSION_1_1"
 	Version Version
 	AddRem  []*specAddRemSet
 }
@@ -131,6 +176,8 @@ type SpecificationExtension struct {
 	Name      string
 	APIRegexp *regexp.Regexp
 	AddRem    []*specAddRemSet
+	Platform  string // Window system the extension depends on, if any
+	Depends   string // Features and extensions the extension depends on, if any
 }
 
 func readSpecFile(file string) (*xmlRegistry, error) {
@@ -153,6 +200,9 @@ func readSpecFile(file string) (*xmlRegistry, error) {
 func parseFunctions(commands []xmlCommand) (specFunctions, error) {
 	functions := make(specFunctions)
 	for _, cmd := range commands {
+		if cmd.Alias != "" {
+			continue
+		}
 		cmdName, cmdReturnType, err := parseSignature(cmd.Prototype.Raw)
 		if err != nil {
 			return functions, err
@@ -164,6 +214,13 @@ func parseFunctions(commands []xmlCommand) (specFunctions, error) {
 			if err != nil {
 				return functions, err
 			}
+			if paramType.Callback != nil {
+				// Function pointers declared inline, as OpenCL does, get a
+				// typedef named after the command and parameter
+				paramType.Callback.Name = cmdName + "_" + paramName
+				paramType.Name = paramType.Callback.Name
+				paramType.CDefinition = paramType.Callback.Name
+			}
 			parameter := Parameter{
 				Name: paramName,
 				Type: paramType}
@@ -177,6 +234,38 @@ func parseFunctions(commands []xmlCommand) (specFunctions, error) {
 			Parameters: parameters,
 			Return:     cmdReturnType}
 	}
+
+	// Vulkan declares commands sharing the signature of another command, e.g.,
+	// those promoted from extensions, as aliases
+	for _, cmd := range commands {
+		if cmd.Alias == "" {
+			continue
+		}
+		aliased := functions.get(cmd.Alias, cmd.API)
+		if aliased == nil {
+			return functions, fmt.Errorf("command %s aliases unknown command %s", cmd.Name, cmd.Alias)
+		}
+		functions[specRef{cmd.Name, cmd.API}] = &Function{
+			Name:       cmd.Name,
+			GoName:     TrimAPIPrefix(cmd.Name),
+			Parameters: append([]Parameter(nil), aliased.Parameters...),
+			Return:     aliased.Return}
+	}
+
+	// OpenGL declares commands equivalent to another command, e.g., those of
+	// ARB extensions promoted to core, with an alias element
+	for _, cmd := range commands {
+		if cmd.AliasOf.Name == "" {
+			continue
+		}
+		name, _, err := parseSignature(cmd.Prototype.Raw)
+		if err != nil {
+			return functions, err
+		}
+		if aliased := functions.get(cmd.AliasOf.Name, cmd.API); aliased != nil {
+			aliased.Aliases = append(aliased.Aliases, name)
+		}
+	}
 	return functions, nil
 }
 
@@ -230,6 +319,7 @@ func overloadFunction(function *Function, info xmlOverload) error {
 func parseSignature(signature xmlSignature) (name string, ctype Type, err error) {
 	readingName := false
 	readingType := false
+	declaration := ""
 
 	decoder := xml.NewDecoder(bytes.NewBuffer(signature))
 	for {
@@ -243,6 +333,7 @@ func parseSignature(signature xmlSignature) (name string, ctype Type, err error)
 		switch t := token.(type) {
 		case xml.CharData:
 			raw := strings.Trim(string(t), " ")
+			declaration += string(t)
 			if readingName {
 				name = raw
 			} else if readingType {
@@ -254,7 +345,8 @@ func parseSignature(signature xmlSignature) (name string, ctype Type, err error)
 				ctype.PointerLevel += strings.Count(raw, "*")
 			}
 		case xml.StartElement:
-			if t.Name.Local == "ptype" {
+			// OpenCL uses type rather than ptype
+			if t.Name.Local == "ptype" || t.Name.Local == "type" {
 				readingType = true
 			} else if t.Name.Local == "name" {
 				readingName = true
@@ -262,7 +354,7 @@ func parseSignature(signature xmlSignature) (name string, ctype Type, err error)
 				return name, ctype, fmt.Errorf("unexpected signature XML: %s", signature)
 			}
 		case xml.EndElement:
-			if t.Name.Local == "ptype" {
+			if t.Name.Local == "ptype" || t.Name.Local == "type" {
 				readingType = false
 			} else if t.Name.Local == "name" {
 				readingName = false
@@ -270,6 +362,16 @@ func parseSignature(signature xmlSignature) (name string, ctype Type, err error)
 		}
 	}
 
+	// Function pointers may be declared inline, e.g.,
+	// "void (CL_CALLBACK* pfn_notify)(const char* errinfo, ...)"
+	if strings.Contains(declaration, "(") {
+		callback, err := parseCallback("typedef " + strings.TrimSpace(declaration) + ";")
+		if err != nil || callback == nil {
+			return name, ctype, fmt.Errorf("unexpected function pointer signature XML: %s", signature)
+		}
+		return name, Type{Name: callback.Name, CDefinition: callback.Name, Callback: callback}, nil
+	}
+
 	// If the XML did not call out the name then parse it out
 	if ctype.Name == "" {
 		cTypeName := ctype.CDefinition
@@ -279,12 +381,15 @@ func parseSignature(signature xmlSignature) (name string, ctype Type, err error)
 		ctype.Name = cTypeName
 	}
 
-	// Convert statically sized arrays to pointers
-	// FIXME: Preserve this type information
+	// Convert statically sized arrays to pointers as C passes them, keeping
+	// the size of single dimension arrays for the Go type
 	arrayRegexp := regexp.MustCompile("\\[.*]")
 	ctype.CDefinition = arrayRegexp.ReplaceAllStringFunc(
 		ctype.CDefinition,
-		func(_ string) string {
+		func(array string) string {
+			if size, err := strconv.Atoi(strings.TrimSpace(array[1 : len(array)-1])); err == nil && size > 0 {
+				ctype.ArraySize = size
+			}
 			ctype.PointerLevel += 1
 			return "*"
 		})
@@ -292,42 +397,183 @@ func parseSignature(signature xmlSignature) (name string, ctype Type, err error)
 	return name, ctype, nil
 }
 
-func parseEnums(enumSets []xmlEnumSet) (specEnums, error) {
+func parseEnums(enumSets []xmlEnumSet) (specEnums, specEnumTypes, error) {
 	enums := make(specEnums)
+	enumTypes := make(specEnumTypes)
 	for _, set := range enumSets {
+		// Vulkan declares the enums of each enumerated type in a set of its own
+		var enumType *specEnumType
+		if set.Type == "enum" || set.Type == "bitmask" {
+			enumType = &specEnumType{bitwidth: set.Bitwidth}
+			enumTypes[set.Name] = enumType
+		}
 		for _, enum := range set.Enums {
+			value, err := parseEnumValue(enum.Name, enum.Value, enum.Bitpos)
+			if err != nil {
+				return nil, nil, err
+			}
 			enumRef := specRef{enum.Name, enum.API}
 			enums[enumRef] = &Enum{
 				Name:   enum.Name,
 				GoName: TrimAPIPrefix(enum.Name),
-				Value:  enum.Value}
+				Value:  value,
+				Alias:  enum.Alias}
+			if enumType != nil {
+				enumType.enums = append(enumType.enums, enum.Name)
+			}
+		}
+	}
+	return enums, enumTypes, nil
+}
+
+var (
+	complementRegexp = regexp.MustCompile(`^\(~(\d+)(U|ULL)\)$`)
+	unsignedRegexp   = regexp.MustCompile(`^(-?(?:0x[0-9A-Fa-f]+|\d+))U(?:LL)?$`)
+	floatRegexp      = regexp.MustCompile(`^(-?\d+\.\d*)F$`)
+)
+
+// parseEnumValue returns the value of an enum as a Go constant expression.
+// OpenCL and Vulkan define bitfield values through the set bit, and Vulkan
+// writes some values as C expressions, e.g., "(~0U)" or "1000.0F". EGL casts
+// some values to its types, e.g., "EGL_CAST(EGLDisplay,0)".
+func parseEnumValue(name, value, bitpos string) (string, error) {
+	if value == "" && bitpos != "" {
+		bit, err := strconv.ParseUint(bitpos, 10, 6)
+		if err != nil {
+			return "", fmt.Errorf("invalid bitpos of enum %s: %v", name, err)
+		}
+		return fmt.Sprintf("0x%X", uint64(1)<<bit), nil
+	}
+	if match := complementRegexp.FindStringSubmatch(value); match != nil {
+		n, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid value of enum %s: %v", name, err)
+		}
+		if match[2] == "U" {
+			return fmt.Sprintf("0x%X", ^uint32(n)), nil
+		}
+		return fmt.Sprintf("0x%X", ^n), nil
+	}
+	if match := unsignedRegexp.FindStringSubmatch(value); match != nil {
+		return match[1], nil
+	}
+	if match := floatRegexp.FindStringSubmatch(value); match != nil {
+		return match[1], nil
+	}
+	return windowSystemEnumValue(value), nil
+}
+
+// parseRequiredEnums adds the enums Vulkan defines where features and
+// extensions require them, e.g., values extending VkStructureType.
+func parseRequiredEnums(enums specEnums, features []xmlFeature, extensions []xmlExtension) error {
+	add := func(requires []xmlRequire, extnumber string) error {
+		for _, req := range requires {
+			for _, ref := range req.Enums {
+				if ref.Value == "" && ref.Bitpos == "" && ref.Offset == "" && ref.Alias == "" {
+					continue
+				}
+				enumRef := specRef{ref.Name, ref.API}
+				if _, ok := enums[enumRef]; ok {
+					continue
+				}
+				value, err := parseEnumValue(ref.Name, ref.Value, ref.Bitpos)
+				if err != nil {
+					return err
+				}
+				if ref.Offset != "" {
+					number := extnumber
+					if ref.Extnumber != "" {
+						number = ref.Extnumber
+					}
+					if value, err = extensionEnumValue(number, ref.Offset, ref.Dir); err != nil {
+						return fmt.Errorf("invalid offset of enum %s: %v", ref.Name, err)
+					}
+				}
+				enums[enumRef] = &Enum{
+					Name:   ref.Name,
+					GoName: TrimAPIPrefix(ref.Name),
+					Value:  value,
+					Alias:  ref.Alias}
+			}
 		}
+		return nil
+	}
+	for _, feature := range features {
+		if err := add(feature.Requires, ""); err != nil {
+			return err
+		}
+	}
+	for _, extension := range extensions {
+		if err := add(extension.Requires, extension.Number); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extensionEnumValue returns the value of the enum at offset within the block
+// of values Vulkan reserves for each extension.
+func extensionEnumValue(extnumber, offset, dir string) (string, error) {
+	number, err := strconv.Atoi(extnumber)
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(offset)
+	if err != nil {
+		return "", err
+	}
+	value := 1000000000 + (number-1)*1000 + n
+	if dir == "-" {
+		value = -value
 	}
-	return enums, nil
+	return strconv.Itoa(value), nil
 }
 
-func parseTypedefs(types []xmlType) (specTypedefs, error) {
+func parseTypedefs(types []xmlType, enums specEnums, enumTypes specEnumTypes) (specTypedefs, error) {
 	typedefs := make(specTypedefs)
 	for i, xtype := range types {
-		typedef, err := parseTypedef(xtype)
+		typedef, requires, err := parseTypedef(xtype, enums, enumTypes)
 		if err != nil {
 			return nil, err
 		}
+		if xtype.Requires != "" {
+			requires = append([]string{xtype.Requires}, requires...)
+		}
+		if xtype.Bitvalues != "" {
+			requires = append([]string{xtype.Bitvalues}, requires...)
+		}
 		typedefRef := specRef{typedef.Name, xtype.API}
 		typedefs[typedefRef] = &specTypedef{
 			typedef:  typedef,
 			ordinal:  i,
-			requires: xtype.Requires}
+			requires: requires}
 	}
 	return typedefs, nil
 }
 
-func parseTypedef(xmlType xmlType) (*Typedef, error) {
+// parseTypedef parses a type of the registry. It also returns the names of
+// the types the definition refers to, which OpenCL and Vulkan mark up as type
+// elements.
+func parseTypedef(xmlType xmlType, enums specEnums, enumTypes specEnumTypes) (*Typedef, []string, error) {
 	typedef := &Typedef{
 		Name:        xmlType.Name,
+		Category:    xmlType.Category,
 		CDefinition: ""}
 
+	// Vulkan declares aliases, e.g., of types promoted from extensions, through
+	// an attribute
+	if xmlType.Alias != "" {
+		typedef.CDefinition = fmt.Sprintf("typedef %s %s;", xmlType.Alias, xmlType.Name)
+		typedef.Base = xmlType.Alias
+		return typedef, []string{xmlType.Alias}, nil
+	}
+
+	var requires, members []string
+	var proto *Parameter
+	var params []Parameter
+	membersParsed := true
 	readingName := false
+	readingType := false
 	decoder := xml.NewDecoder(bytes.NewBuffer(xmlType.Raw))
 	for {
 		token, err := decoder.Token()
@@ -335,33 +581,374 @@ func parseTypedef(xmlType xmlType) (*Typedef, error) {
 			break
 		}
 		if err != nil {
-			return typedef, err
+			return typedef, nil, err
 		}
 		switch t := token.(type) {
 		case xml.CharData:
 			raw := string(t)
+			if readingType {
+				requires = append(requires, raw)
+			}
 			typedef.CDefinition += raw
 			if readingName {
 				typedef.Name = raw
 			}
 		case xml.StartElement:
-			if t.Name.Local == "name" {
+			switch t.Name.Local {
+			case "name":
 				readingName = true
-			} else if t.Name.Local == "apientry" {
+			case "apientry":
 				typedef.CDefinition += "APIENTRY"
-			} else {
-				return typedef, fmt.Errorf("unexpected typedef XML: %s", xmlType.Raw)
+			case "type":
+				readingType = true
+			case "comment":
+				if err := decoder.Skip(); err != nil {
+					return typedef, nil, err
+				}
+			case "member", "proto", "param":
+				var element struct {
+					Raw xmlSignature `xml:",innerxml"`
+				}
+				if err := decoder.DecodeElement(&element, &t); err != nil {
+					return typedef, nil, err
+				}
+				if t.Name.Local == "member" {
+					member, declaration, ok := parseMember(element.Raw, enums)
+					members = append(members, declaration)
+					membersParsed = membersParsed && ok
+					typedef.Members = append(typedef.Members, member)
+					if member.Type.Name != "" {
+						requires = append(requires, member.Type.Name)
+					}
+					continue
+				}
+				// Function pointers may list their return type and parameters
+				name, ctype, err := parseSignature(element.Raw)
+				if err != nil {
+					return typedef, nil, err
+				}
+				if t.Name.Local == "proto" {
+					typedef.Name = name
+					proto = &Parameter{Name: name, Type: ctype}
+				} else {
+					params = append(params, Parameter{Name: name, Type: ctype})
+				}
+				requires = append(requires, ctype.Name)
+			default:
+				return typedef, nil, fmt.Errorf("unexpected typedef XML: %s", xmlType.Raw)
 			}
 		case xml.EndElement:
 			if t.Name.Local == "name" {
 				readingName = false
+			} else if t.Name.Local == "type" {
+				readingType = false
 			}
 		default:
-			return typedef, fmt.Errorf("unexpected typedef XML: %s", xmlType.Raw)
+			return typedef, nil, fmt.Errorf("unexpected typedef XML: %s", xmlType.Raw)
+		}
+	}
+
+	switch xmlType.Category {
+	case "struct", "union":
+		// OpenCL and Vulkan list the members of structures rather than their
+		// definition
+		definition := fmt.Sprintf("typedef %s _%s {\n", xmlType.Category, typedef.Name)
+		for _, member := range members {
+			// Members such as Vulkan's pNext refer to structures by their tag
+			definition += "    " + structTagRegexp.ReplaceAllString(member, "struct _$1") + ";\n"
+		}
+		typedef.CDefinition = definition + "} " + typedef.Name + ";"
+		if !membersParsed {
+			// Without the layout of every member there is no Go mirror
+			typedef.Category = ""
+			typedef.Members = nil
+		}
+	case "handle":
+		// Non-dispatchable handles are 64-bit integers, see CTypedef
+		if len(requires) > 0 && requires[0] == "VK_DEFINE_NON_DISPATCHABLE_HANDLE" {
+			typedef.Base = "uint64_t"
+			requires = append(requires, typedef.Base)
 		}
+	case "enum":
+		// Vulkan leaves the definition of enumerated types to their enums
+		if strings.TrimSpace(typedef.CDefinition) == "" {
+			typedef.Base = "int32_t"
+			if enumType := enumTypes[typedef.Name]; enumType != nil && enumType.bitwidth == 64 {
+				typedef.Base = "uint64_t"
+			}
+			typedef.CDefinition = fmt.Sprintf("typedef %s %s;", typedef.Base, typedef.Name)
+			requires = append(requires, typedef.Base)
+		}
+	case "include":
+		if strings.TrimSpace(typedef.CDefinition) == "" {
+			typedef.CDefinition = fmt.Sprintf("#include <%s>", typedef.Name)
+		}
+	case "funcpointer":
+		if proto != nil {
+			callback := &Callback{Name: proto.Name, Return: proto.Type, Parameters: params, Convention: "VKAPI_PTR"}
+			typedef.CDefinition = callback.CTypedef()
+		}
+	}
+
+	// Keep track of aliases such as "typedef cl_uint cl_bool;"
+	if match := aliasRegexp.FindStringSubmatch(strings.TrimSpace(typedef.CDefinition)); match != nil &&
+		len(requires) == 1 && match[1] == requires[0] {
+		typedef.Base = match[1]
+	}
+
+	callback, err := parseCallback(typedef.CDefinition)
+	if err != nil {
+		return typedef, nil, fmt.Errorf("unexpected function pointer typedef %s: %v", typedef.Name, err)
+	}
+	typedef.Callback = callback
+	if callback != nil {
+		// The registry of OpenGL does not mark up the types of the parameters
+		requires = append(requires, callback.Return.Name)
+		for _, param := range callback.Parameters {
+			requires = append(requires, param.Type.Name)
+		}
+	}
+	return typedef, requires, nil
+}
+
+var arrayDimRegexp = regexp.MustCompile(`\[\s*(\w+)\s*\]`)
+var structTagRegexp = regexp.MustCompile(`\bstruct\s+(\w+)`)
+var bitFieldRegexp = regexp.MustCompile(`^\s*:\s*(\d+)\s*$`)
+
+// parseMember parses a member of a struct or union, e.g.,
+// "<type>char</type> <name>deviceName</name>[<enum>VK_MAX_PHYSICAL_DEVICE_NAME_SIZE</enum>]".
+// It returns the member along with its C declaration, in which the constants
+// of array sizes are replaced by their values. The member is only complete if
+// its name, type, array sizes, and bit-field width could all be determined.
+func parseMember(raw xmlSignature, enums specEnums) (Member, string, bool) {
+	var member Member
+	var declaration, typeDeclaration, suffix string
+	reading := ""
+	decoder := xml.NewDecoder(bytes.NewBuffer(raw))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return member, declaration, false
+		}
+		switch t := token.(type) {
+		case xml.CharData:
+			text := string(t)
+			switch reading {
+			case "comment":
+				continue
+			case "name":
+				member.Name = strings.TrimSpace(text)
+			case "enum":
+				if value, ok := enums.intValue(strings.TrimSpace(text)); ok {
+					text = strconv.Itoa(value)
+				}
+				suffix += text
+			case "type":
+				member.Type.Name = strings.TrimSpace(text)
+				typeDeclaration += text
+			default:
+				if member.Name == "" {
+					typeDeclaration += text
+				} else {
+					suffix += text
+				}
+			}
+			declaration += text
+		case xml.StartElement:
+			reading = t.Name.Local
+			switch reading {
+			case "name", "type", "enum", "comment":
+			default:
+				return member, declaration, false
+			}
+		case xml.EndElement:
+			reading = ""
+		}
+	}
+	declaration = strings.TrimSpace(declaration)
+	if member.Name == "" || member.Type.Name == "" {
+		return member, declaration, false
+	}
+
+	name := member.Type.Name
+	member.Type = parseCType(typeDeclaration)
+	member.Type.Name = name
+
+	// Array sizes may also refer to constants without marking them up
+	for _, dim := range arrayDimRegexp.FindAllStringSubmatch(suffix, -1) {
+		size, err := strconv.Atoi(dim[1])
+		if err != nil {
+			var ok bool
+			if size, ok = enums.intValue(dim[1]); !ok {
+				return member, declaration, false
+			}
+		}
+		member.Dims = append(member.Dims, size)
+	}
+	rest := arrayDimRegexp.ReplaceAllString(suffix, "")
+	if match := bitFieldRegexp.FindStringSubmatch(rest); match != nil {
+		member.Bits, _ = strconv.Atoi(match[1])
+	} else if strings.TrimSpace(rest) != "" {
+		return member, declaration, false
+	}
+	return member, declaration, true
+}
+
+var aliasRegexp = regexp.MustCompile(`^typedef\s+(\w+)\s+(\w+)\s*;$`)
+
+var callbackRegexp = regexp.MustCompile(`(?s)^typedef\s+(.+?)\s*\(\s*(?:(APIENTRY|CL_CALLBACK|VKAPI_PTR)\s*)?\*\s*(\w+)\s*\)\s*\((.*)\)\s*;$`)
+
+// parseCallback parses the signature of a function pointer typedef, e.g.,
+// "typedef void (APIENTRY *GLDEBUGPROC)(GLenum source, ...);". It returns nil
+// if the typedef does not define a function pointer.
+func parseCallback(definition string) (*Callback, error) {
+	match := callbackRegexp.FindStringSubmatch(strings.TrimSpace(definition))
+	if match == nil {
+		return nil, nil
+	}
+	callback := &Callback{
+		Name:       match[3],
+		Return:     parseCType(match[1]),
+		Convention: match[2],
 	}
+	if params := strings.TrimSpace(match[4]); params != "void" && params != "" {
+		for _, param := range strings.Split(params, ",") {
+			param = strings.TrimSpace(param)
+			i := strings.LastIndexFunc(param, func(r rune) bool {
+				return !(r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r))
+			})
+			if i < 0 {
+				return nil, fmt.Errorf("unnamed parameter %q", param)
+			}
+			callback.Parameters = append(callback.Parameters, Parameter{
+				Name: param[i+1:],
+				Type: parseCType(param[:i+1]),
+			})
+		}
+	}
+	return callback, nil
+}
 
-	return typedef, nil
+// parseCType parses a C type such as "const GLchar *".
+func parseCType(definition string) Type {
+	definition = strings.TrimSpace(definition)
+	name := strings.Replace(definition, "const", "", -1)
+	name = strings.Replace(name, "*", "", -1)
+	return Type{
+		Name:         strings.TrimSpace(name),
+		PointerLevel: strings.Count(definition, "*"),
+		CDefinition:  definition,
+	}
+}
+
+// linkCallbacks attaches the signatures of function pointer typedefs to the
+// parameters of that type.
+func (spec *Specification) linkCallbacks() {
+	link := func(params []Parameter, api string) {
+		for i := range params {
+			typedef, ok := spec.Typedefs[specRef{params[i].Type.Name, api}]
+			if !ok {
+				typedef, ok = spec.Typedefs[specRef{params[i].Type.Name, ""}]
+			}
+			if ok {
+				params[i].Type.Callback = typedef.typedef.Callback
+			}
+		}
+	}
+	for ref, fn := range spec.Functions {
+		link(fn.Parameters, ref.api)
+		for _, overload := range fn.Overloads {
+			link(overload.Parameters, ref.api)
+		}
+	}
+}
+
+// addCallbackTypedefs declares typedefs for the function pointers declared
+// inline by command parameters so that they can be referred to by name.
+func (spec *Specification) addCallbackTypedefs() {
+	var callbacks []*Callback
+	for _, fn := range spec.Functions {
+		for _, param := range fn.Parameters {
+			if param.Type.Callback != nil && spec.Typedefs.get(param.Type.Name, "") == nil {
+				callbacks = append(callbacks, param.Type.Callback)
+			}
+		}
+	}
+	sort.Slice(callbacks, func(i, j int) bool { return callbacks[i].Name < callbacks[j].Name })
+
+	ordinal := len(spec.Typedefs)
+	for _, callback := range callbacks {
+		if spec.Typedefs.get(callback.Name, "") != nil {
+			continue
+		}
+		requires := []string{callback.Return.Name}
+		for _, param := range callback.Parameters {
+			requires = append(requires, param.Type.Name)
+		}
+		spec.Typedefs[specRef{callback.Name, ""}] = &specTypedef{
+			typedef: &Typedef{
+				Name:        callback.Name,
+				CDefinition: callback.CTypedef(),
+				Callback:    callback,
+			},
+			ordinal:  ordinal,
+			requires: requires,
+		}
+		ordinal++
+	}
+}
+
+// resolveAliases records the type that types such as cl_device_type
+// ultimately alias, e.g., cl_ulong, along with its category for the purpose
+// of mapping them to Go. It also resolves the values of aliased enums.
+func (spec *Specification) resolveAliases() {
+	windowSystem := spec.windowSystemAPI()
+	resolve := func(t *Type, api string) {
+		if _, ok := windowSystemTypes[windowSystem][t.Name]; ok {
+			t.WindowSystem = windowSystem
+		}
+		typedef := spec.Typedefs.get(strings.TrimPrefix(t.Name, "struct "), api)
+		for ; typedef != nil && typedef.typedef.Base != ""; typedef = spec.Typedefs.get(typedef.typedef.Base, api) {
+			t.Underlying = typedef.typedef.Base
+		}
+		if typedef != nil {
+			t.Category = typedef.typedef.Category
+		}
+	}
+	resolveAll := func(params []Parameter, api string) {
+		for i := range params {
+			resolve(&params[i].Type, api)
+		}
+	}
+	for ref, fn := range spec.Functions {
+		resolve(&fn.Return, ref.api)
+		resolveAll(fn.Parameters, ref.api)
+		for i := range fn.Overloads {
+			resolve(&fn.Overloads[i].Return, ref.api)
+			resolveAll(fn.Overloads[i].Parameters, ref.api)
+		}
+	}
+	for ref, typedef := range spec.Typedefs {
+		if callback := typedef.typedef.Callback; callback != nil {
+			resolve(&callback.Return, ref.api)
+			resolveAll(callback.Parameters, ref.api)
+		}
+		for i := range typedef.typedef.Members {
+			resolve(&typedef.typedef.Members[i].Type, ref.api)
+		}
+	}
+	for ref, enum := range spec.Enums {
+		// EGL aliases keep a value of their own, such as EGL_CAST(EGLSyncKHR, 0)
+		for aliased := enum; aliased != nil && enum.Value == ""; {
+			aliased = spec.Enums.get(aliased.Alias, ref.api)
+			if aliased != nil {
+				enum.Value = aliased.Value
+			}
+		}
+	}
 }
 
 func parseFeatures(xmlFeatures []xmlFeature) ([]SpecificationFeature, error) {
@@ -371,45 +958,54 @@ func parseFeatures(xmlFeatures []xmlFeature) ([]SpecificationFeature, error) {
 		if err != nil {
 			return features, err
 		}
-		feature := SpecificationFeature{
-			API:     xmlFeature.API,
-			Version: version,
-			AddRem:  parseAddRem(xmlFeature.Requires, xmlFeature.Removes),
+		// Vulkan features may apply to several APIs, e.g., "vulkan,vulkansc"
+		for _, api := range strings.Split(xmlFeature.API, ",") {
+			feature := SpecificationFeature{
+				API:     api,
+				Name:    xmlFeature.Name,
+				Version: version,
+				AddRem:  parseAddRem(xmlFeature.Requires, xmlFeature.Removes),
+			}
+			features = append(features, feature)
 		}
-		features = append(features, feature)
 	}
 	return features, nil
 }
 
 func parseAddRem(requires []xmlRequire, removes []xmlRemove) []*specAddRemSet {
-	addRemByProfile := make(map[string]*specAddRemSet)
+	type addRemKey struct{ profile, depends string }
+	addRemByProfile := make(map[addRemKey]*specAddRemSet)
 
-	addRemForProfile := func(profile string) *specAddRemSet {
-		addRem, ok := addRemByProfile[profile]
+	addRemForProfile := func(profile, depends string) *specAddRemSet {
+		addRem, ok := addRemByProfile[addRemKey{profile, depends}]
 		if !ok {
 			addRem = &specAddRemSet{
 				profile:         profile,
+				depends:         depends,
 				addedEnums:      make([]string, 0),
 				addedCommands:   make([]string, 0),
 				removedEnums:    make([]string, 0),
 				removedCommands: make([]string, 0),
 			}
-			addRemByProfile[profile] = addRem
+			addRemByProfile[addRemKey{profile, depends}] = addRem
 		}
 		return addRem
 	}
 
 	for _, req := range requires {
-		addRem := addRemForProfile(req.Profile)
+		addRem := addRemForProfile(req.Profile, req.Depends)
 		for _, cmd := range req.Commands {
 			addRem.addedCommands = append(addRem.addedCommands, cmd.Name)
 		}
 		for _, enum := range req.Enums {
 			addRem.addedEnums = append(addRem.addedEnums, enum.Name)
 		}
+		for _, t := range req.Types {
+			addRem.addedTypes = append(addRem.addedTypes, t.Name)
+		}
 	}
 	for _, rem := range removes {
-		addRem := addRemForProfile(rem.Profile)
+		addRem := addRemForProfile(rem.Profile, "")
 		for _, cmd := range rem.Commands {
 			addRem.removedCommands = append(addRem.removedCommands, cmd.Name)
 		}
@@ -431,10 +1027,14 @@ func parseExtensions(xmlExtensions []xmlExtension) ([]SpecificationExtension, er
 		if len(xmlExtension.Removes) > 0 {
 			return nil, fmt.Errorf("unexpected extension with removal requirement: %s", xmlExtension)
 		}
+		// Vulkan separates the supported APIs by commas rather than bars
+		supported := strings.Replace(xmlExtension.Supported, ",", "|", -1)
 		extension := SpecificationExtension{
 			Name:      xmlExtension.Name,
-			APIRegexp: regexp.MustCompile("^(" + xmlExtension.Supported + ")$"),
+			APIRegexp: regexp.MustCompile("^(" + supported + ")$"),
 			AddRem:    parseAddRem(xmlExtension.Requires, xmlExtension.Removes),
+			Platform:  xmlExtension.Platform,
+			Depends:   xmlExtension.Depends,
 		}
 		extensions = append(extensions, extension)
 	}
@@ -466,15 +1066,34 @@ func (enums specEnums) get(name, api string) *Enum {
 	return enums[specRef{name, ""}]
 }
 
+// intValue returns the integer value of the named enum, following aliases.
+func (enums specEnums) intValue(name string) (int, bool) {
+	for enum := enums.get(name, ""); enum != nil; enum = enums.get(enum.Alias, "") {
+		if enum.Value != "" {
+			value, err := strconv.ParseInt(enum.Value, 0, 0)
+			return int(value), err == nil
+		}
+	}
+	return 0, false
+}
+
+func (typedefs specTypedefs) get(name, api string) *specTypedef {
+	typedef, ok := typedefs[specRef{name, api}]
+	if ok {
+		return typedef
+	}
+	return typedefs[specRef{name, ""}]
+}
+
 func (typedefs specTypedefs) selectRequired(name, api string, requiredTypedefs []*Typedef) {
 	specTypedef, ok := typedefs[specRef{name, api}]
 	if !ok {
 		specTypedef = typedefs[specRef{name, ""}]
 	}
-	if specTypedef != nil {
+	if specTypedef != nil && requiredTypedefs[specTypedef.ordinal] == nil {
 		requiredTypedefs[specTypedef.ordinal] = specTypedef.typedef
-		if specTypedef.requires != "" {
-			typedefs.selectRequired(specTypedef.requires, api, requiredTypedefs)
+		for _, required := range specTypedef.requires {
+			typedefs.selectRequired(required, api, requiredTypedefs)
 		}
 	}
 }
@@ -515,6 +1134,212 @@ func (addRem *specAddRemSet) shouldInclude(pkgSpec *PackageSpec) bool {
 	return true
 }
 
+// includedExtensions returns the names of the extensions to generate. Only
+// extensions whose dependencies are available are included.
+func (spec *Specification) includedExtensions(pkgSpec *PackageSpec) map[string]bool {
+	included := make(map[string]bool)
+	for _, extension := range spec.Extensions {
+		if !extension.shouldInclude(pkgSpec) {
+			continue
+		}
+		forced := pkgSpec.AddExtRegexp != nil && pkgSpec.AddExtRegexp.MatchString(extension.Name)
+		if !forced && pkgSpec.API == "vulkan" && (extension.Platform != "" || spec.requiresHeader(extension, pkgSpec.API)) {
+			// Window system and video extensions depend on other headers
+			continue
+		}
+		if !forced && windowSystemTypes[pkgSpec.API] != nil && spec.requiresHeader(extension, pkgSpec.API) {
+			// So do SGI extensions to GLX
+			continue
+		}
+		included[extension.Name] = true
+	}
+
+	// Dropping an extension may leave others depending on it unsatisfied
+	for changed := true; changed; {
+		changed = false
+		for _, extension := range spec.Extensions {
+			if included[extension.Name] && !spec.satisfies(extension.Depends, pkgSpec, included) {
+				delete(included, extension.Name)
+				changed = true
+			}
+		}
+	}
+	return included
+}
+
+// requiresHeader determines whether an extension requires types declared in
+// headers other than the platform header of the API.
+func (spec *Specification) requiresHeader(extension SpecificationExtension, api string) bool {
+	visited := make(map[string]bool)
+	var requires func(name string) bool
+	requires = func(name string) bool {
+		if visited[name] {
+			return false
+		}
+		visited[name] = true
+		typedef := spec.Typedefs.get(name, api)
+		if typedef == nil {
+			return false
+		}
+		if typedef.typedef.Category == "include" && typedef.typedef.Name != "vk_platform" {
+			return true
+		}
+		for _, required := range typedef.requires {
+			if requires(required) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, addRem := range extension.AddRem {
+		for _, name := range addRem.addedTypes {
+			if requires(name) {
+				return true
+			}
+		}
+		for _, cmd := range addRem.addedCommands {
+			fn := spec.Functions.get(cmd, api)
+			if fn == nil {
+				continue
+			}
+			if requires(fn.Return.Name) {
+				return true
+			}
+			for _, param := range fn.Parameters {
+				if requires(param.Type.Name) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// satisfies evaluates a dependency expression such as
+// "VK_KHR_surface+(VK_VERSION_1_1,VK_KHR_get_physical_device_properties2)",
+// where "+" requires both operands and "," either. Names refer to features
+// and the included extensions.
+func (spec *Specification) satisfies(depends string, pkgSpec *PackageSpec, extensions map[string]bool) bool {
+	if depends == "" {
+		return true
+	}
+	available := func(name string) bool {
+		if extensions[name] {
+			return true
+		}
+		for _, feature := range spec.Features {
+			if feature.Name == name && feature.shouldInclude(pkgSpec) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Parse the expression recursively; "+" binds tighter than ","
+	pos := 0
+	var parseOr func() bool
+	parseTerm := func() bool {
+		if pos < len(depends) && depends[pos] == '(' {
+			pos++
+			value := parseOr()
+			pos++ // Skip ")"
+			return value
+		}
+		start := pos
+		for pos < len(depends) && !strings.ContainsRune("+,()", rune(depends[pos])) {
+			pos++
+		}
+		return available(strings.TrimSpace(depends[start:pos]))
+	}
+	parseAnd := func() bool {
+		value := parseTerm()
+		for pos < len(depends) && depends[pos] == '+' {
+			pos++
+			value = parseTerm() && value
+		}
+		return value
+	}
+	parseOr = func() bool {
+		value := parseAnd()
+		for pos < len(depends) && depends[pos] == ',' {
+			pos++
+			value = parseAnd() || value
+		}
+		return value
+	}
+	return parseOr()
+}
+
+// selectRequiredType adds a type listed in a requirement to the package,
+// together with its enums if it is an enumerated type. Other types are only
+// added when functions refer to them.
+func (spec *Specification) selectRequiredType(name string, pkg *Package, version Version, extension string) {
+	typedef := spec.Typedefs.get(name, pkg.API)
+	if typedef == nil {
+		return
+	}
+	switch typedef.typedef.Category {
+	case "struct", "union", "enum":
+	default:
+		return
+	}
+	spec.Typedefs.selectRequired(name, pkg.API, pkg.Typedefs)
+
+	enumType, ok := spec.EnumTypes[name]
+	if !ok {
+		return
+	}
+	for _, enum := range enumType.enums {
+		if _, ok := pkg.Enums[enum]; ok {
+			continue
+		}
+		e := *spec.Enums.get(enum, pkg.API)
+		e.Version = version
+		e.Extension = extension
+		pkg.Enums[enum] = &e
+	}
+}
+
+// sortRequired compacts the selected typedefs, indexed by ordinal, into
+// declaration order. Typedefs are declared in the order of the registry
+// unless they require typedefs declared later.
+func (typedefs specTypedefs) sortRequired(requiredTypedefs []*Typedef, api string) []*Typedef {
+	ordinals := make(map[*Typedef]int)
+	for ordinal, typedef := range requiredTypedefs {
+		if typedef != nil {
+			ordinals[typedef] = ordinal
+		}
+	}
+	sorted := make([]*Typedef, 0, len(ordinals))
+	visited := make(map[*Typedef]bool)
+	var visit func(typedef *Typedef)
+	visit = func(typedef *Typedef) {
+		if visited[typedef] {
+			return
+		}
+		visited[typedef] = true
+		specTypedef := typedefs.get(typedef.Name, api)
+		if specTypedef != nil && specTypedef.typedef == typedef {
+			for _, required := range specTypedef.requires {
+				dependency := typedefs.get(required, api)
+				if dependency == nil {
+					continue
+				}
+				if _, ok := ordinals[dependency.typedef]; ok {
+					visit(dependency.typedef)
+				}
+			}
+		}
+		sorted = append(sorted, typedef)
+	}
+	for _, typedef := range requiredTypedefs {
+		if typedef != nil {
+			visit(typedef)
+		}
+	}
+	return sorted
+}
+
 // NewSpecification creates a new specification based on an XML registry.
 func NewSpecification(registry xmlRegistry, overloads xmlOverloads) (*Specification, error) {
 	functions, err := parseFunctions(registry.Commands)
@@ -527,12 +1352,17 @@ func NewSpecification(registry xmlRegistry, overloads xmlOverloads) (*Specificat
 		return nil, err
 	}
 
-	enums, err := parseEnums(registry.Enums)
+	enums, enumTypes, err := parseEnums(registry.Enums)
 	if err != nil {
 		return nil, err
 	}
 
-	typedefs, err := parseTypedefs(registry.Types)
+	err = parseRequiredEnums(enums, registry.Features, registry.Extensions)
+	if err != nil {
+		return nil, err
+	}
+
+	typedefs, err := parseTypedefs(registry.Types, enums, enumTypes)
 	if err != nil {
 		return nil, err
 	}
@@ -551,35 +1381,235 @@ func NewSpecification(registry xmlRegistry, overloads xmlOverloads) (*Specificat
 		Functions:  functions,
 		Enums:      enums,
 		Typedefs:   typedefs,
+		EnumTypes:  enumTypes,
 		Features:   features,
 		Extensions: extensions,
 	}
+	spec.declareWindowSystemTypes()
+	spec.addCallbackTypedefs()
+	spec.linkCallbacks()
+	spec.resolveAliases()
 	return spec, nil
 }
 
 // HasPackage determines whether the specification can generate the specified package.
 func (spec *Specification) HasPackage(pkgSpec *PackageSpec) bool {
-	for _, feature := range spec.Features {
-		if pkgSpec.API == feature.API && pkgSpec.Version.Compare(feature.Version) == 0 {
-			return true
+	hasVersion := func(version Version) bool {
+		for _, feature := range spec.Features {
+			if pkgSpec.API == feature.API && version.Compare(feature.Version) == 0 {
+				return true
+			}
 		}
+		return false
 	}
-	return false
+	if pkgSpec.MinVersion != (Version{}) && !hasVersion(pkgSpec.MinVersion) {
+		return false
+	}
+	return hasVersion(pkgSpec.Version)
+}
+
+// addAliasFallbacks lists, for each function of a feature version of the
+// package, the aliases Init binds if the function is missing. Only the
+// aliases added by extensions of the API qualify.
+func (spec *Specification) addAliasFallbacks(pkg *Package, pkgSpec *PackageSpec) {
+	supported := make(map[string]bool)
+	for _, extension := range spec.Extensions {
+		if !extension.shouldInclude(pkgSpec) {
+			continue
+		}
+		for _, addRem := range extension.AddRem {
+			for _, cmd := range addRem.addedCommands {
+				supported[cmd] = true
+			}
+		}
+	}
+	for _, fn := range pkg.Functions {
+		if fn.Extension != "" {
+			continue
+		}
+		var fallbacks []string
+		for _, alias := range fn.Aliases {
+			if supported[alias] {
+				fallbacks = append(fallbacks, alias)
+			}
+		}
+		fn.Fallbacks = mergeFallbacks(nil, fallbacks)
+	}
+}
+
+// mergeFallbacks adds the aliases missing from fallbacks to it, ordered by
+// preference: those of ARB, KHR, OES, and EXT extensions before vendor ones.
+func mergeFallbacks(fallbacks, aliases []string) []string {
+	for _, alias := range aliases {
+		found := false
+		for _, fallback := range fallbacks {
+			found = found || fallback == alias
+		}
+		if !found {
+			fallbacks = append(fallbacks, alias)
+		}
+	}
+	rank := func(alias string) int {
+		for i, suffix := range []string{"ARB", "KHR", "OES", "EXT"} {
+			if strings.HasSuffix(alias, suffix) {
+				return i
+			}
+		}
+		return 4
+	}
+	sort.SliceStable(fallbacks, func(i, j int) bool {
+		return rank(fallbacks[i]) < rank(fallbacks[j])
+	})
+	return fallbacks
+}
+
+// ToCombinedPackage generates a package merging the packages of several APIs
+// of the specification, e.g., OpenGL 3.3 core and OpenGL ES 3.0. The package
+// holds the enums, functions, and extensions of any of the packages if union
+// is set, and those common to all of them otherwise. It is named after the
+// first package, whose API it reports as its own.
+func (spec *Specification) ToCombinedPackage(pkgSpecs []*PackageSpec, union bool) *Package {
+	pkgs := make([]*Package, len(pkgSpecs))
+	for i, pkgSpec := range pkgSpecs {
+		pkgs[i] = spec.ToPackage(pkgSpec)
+	}
+	included := func(n int) bool {
+		return n == len(pkgs) || (union && n > 0)
+	}
+
+	pkg := *pkgs[0]
+	pkg.Combined = pkgs
+	pkg.Union = union
+	pkg.Enums = make(map[string]*Enum)
+	pkg.Functions = make(map[string]*PackageFunction)
+	pkg.Extensions = nil
+
+	for _, sub := range pkgs {
+		for name, fn := range sub.Functions {
+			if _, ok := pkg.Functions[name]; ok {
+				continue
+			}
+			apis := make([]*FunctionAPI, len(pkgs))
+			n := 0
+			for i, other := range pkgs {
+				if otherFn, ok := other.Functions[name]; ok {
+					apis[i] = &FunctionAPI{
+						API:       other.API,
+						Required:  otherFn.Required,
+						Version:   otherFn.Version,
+						Extension: otherFn.Extension,
+					}
+					n++
+				}
+			}
+			if !included(n) {
+				continue
+			}
+			combined := *fn
+			combined.APIs = apis
+			for _, other := range pkgs {
+				if otherFn, ok := other.Functions[name]; ok {
+					combined.Fallbacks = mergeFallbacks(combined.Fallbacks, otherFn.Fallbacks)
+				}
+			}
+			combined.Required = combined.RequiredAPIs() != 0
+			pkg.Functions[name] = &combined
+		}
+		for name, enum := range sub.Enums {
+			if _, ok := pkg.Enums[name]; ok {
+				continue
+			}
+			n := 0
+			for _, other := range pkgs {
+				if _, ok := other.Enums[name]; ok {
+					n++
+				}
+			}
+			if included(n) {
+				pkg.Enums[name] = enum
+			}
+		}
+	}
+
+	extensions := make(map[string]int)
+	for _, sub := range pkgs {
+		for _, extension := range sub.Extensions {
+			extensions[extension.Name]++
+			if extensions[extension.Name] == 1 {
+				pkg.Extensions = append(pkg.Extensions, extension)
+			}
+		}
+	}
+	filtered := pkg.Extensions[:0]
+	for _, extension := range pkg.Extensions {
+		if included(extensions[extension.Name]) {
+			filtered = append(filtered, extension)
+		}
+	}
+	pkg.Extensions = filtered
+	sort.Slice(pkg.Extensions, func(i, j int) bool {
+		return pkg.Extensions[i].Name < pkg.Extensions[j].Name
+	})
+
+	// Select the types necessary to declare the functions of every API
+	typedefs := make([]*Typedef, len(spec.Typedefs))
+	for _, fn := range pkg.Functions {
+		for i, api := range fn.APIs {
+			if api == nil {
+				continue
+			}
+			spec.Typedefs.selectRequired(fn.Function.Return.Name, pkgs[i].API, typedefs)
+			for _, param := range fn.Function.Parameters {
+				spec.Typedefs.selectRequired(param.Type.Name, pkgs[i].API, typedefs)
+			}
+		}
+	}
+	pkg.Typedefs = spec.Typedefs.sortRequired(typedefs, pkg.API)
+
+	return &pkg
+}
+
+// appendExtension adds extension to the other extensions of a symbol the
+// first extension added, unless it is one of them already.
+func appendExtension(first string, others []string, extension string) []string {
+	if extension == first {
+		return others
+	}
+	for _, other := range others {
+		if other == extension {
+			return others
+		}
+	}
+	return append(others, extension)
 }
 
 // ToPackage generates a package from the specification.
 func (spec *Specification) ToPackage(pkgSpec *PackageSpec) *Package {
 	pkg := &Package{
-		API:       pkgSpec.API,
-		Name:      pkgSpec.API,
-		Version:   pkgSpec.Version,
-		Profile:   pkgSpec.Profile,
-		TmplDir:   pkgSpec.TmplDir,
-		Typedefs:  make([]*Typedef, len(spec.Typedefs)),
-		Enums:     make(map[string]*Enum),
-		Functions: make(map[string]*PackageFunction),
+		API:        pkgSpec.API,
+		Name:       pkgSpec.API,
+		Version:    pkgSpec.Version,
+		MinVersion: pkgSpec.MinVersion,
+		Profile:    pkgSpec.Profile,
+		TmplDir:    pkgSpec.TmplDir,
+		Backend:    pkgSpec.Backend,
+		Typedefs:   make([]*Typedef, len(spec.Typedefs)),
+		Enums:      make(map[string]*Enum),
+		Functions:  make(map[string]*PackageFunction),
+
+		// Build tags apply to whole files so they require split files
+		SplitFiles:    pkgSpec.SplitFiles || pkgSpec.ExtensionTags,
+		ExtensionTags: pkgSpec.ExtensionTags,
+		StrictVersion: pkgSpec.StrictVersion,
+		LazyInit:      pkgSpec.LazyInit,
+		AliasFallback: pkgSpec.AliasFallback,
+		Headless:      pkgSpec.Headless,
 	}
 
+	// Select the extensions compatible with the specified API version first,
+	// as requirements may depend on them
+	extensions := spec.includedExtensions(pkgSpec)
+
 	// Select the commands and enums relevant to the specified API version
 	for _, feature := range spec.Features {
 		if !feature.shouldInclude(pkgSpec) {
@@ -587,18 +1617,40 @@ func (spec *Specification) ToPackage(pkgSpec *PackageSpec) *Package {
 		}
 		for _, addRem := range feature.AddRem {
 			if !addRem.shouldInclude(pkgSpec) {
+				// Keep the symbols removed by other profiles, marked as such
+				pkg.markRemoved(addRem, feature.Version)
+				continue
+			}
+			if !spec.satisfies(addRem.depends, pkgSpec, extensions) {
 				continue
 			}
+			pkg.markRestored(addRem)
 			for _, cmd := range addRem.addedCommands {
+				// Keep the earliest feature version that introduced the function
+				if _, ok := pkg.Functions[cmd]; ok {
+					continue
+				}
 				pkg.Functions[cmd] = &PackageFunction{
 					Function: *spec.Functions.get(cmd, pkg.API),
-					Required: !pkgSpec.LenientInit,
+					Required: !pkgSpec.LenientInit && !pkg.IsOptionalVersion(feature.Version),
+					Version:  feature.Version,
 				}
 			}
 			for _, enum := range addRem.addedEnums {
-				pkg.Enums[enum] = spec.Enums.get(enum, pkg.API)
+				if _, ok := pkg.Enums[enum]; ok {
+					continue
+				}
+				e := *spec.Enums.get(enum, pkg.API)
+				e.Version = feature.Version
+				pkg.Enums[enum] = &e
+			}
+			for _, name := range addRem.addedTypes {
+				spec.selectRequiredType(name, pkg, feature.Version, "")
 			}
-			if !pkg.Version.IsAll() {
+			if pkg.Version.IsAll() {
+				// Packages of all versions keep removed symbols
+				pkg.markRemoved(addRem, feature.Version)
+			} else {
 				for _, cmd := range addRem.removedCommands {
 					delete(pkg.Functions, cmd)
 				}
@@ -609,30 +1661,50 @@ func (spec *Specification) ToPackage(pkgSpec *PackageSpec) *Package {
 		}
 	}
 
-	// Select the extensions compatible with the specified API version
 	for _, extension := range spec.Extensions {
-		if !extension.shouldInclude(pkgSpec) {
+		if !extensions[extension.Name] {
 			continue
 		}
+		pkg.Extensions = append(pkg.Extensions, &PackageExtension{
+			Name:   extension.Name,
+			GoName: TrimAPIPrefix(extension.Name),
+		})
 		for _, addRem := range extension.AddRem {
-			if !addRem.shouldInclude(pkgSpec) {
+			if !addRem.shouldInclude(pkgSpec) || !spec.satisfies(addRem.depends, pkgSpec, extensions) {
 				continue
 			}
 			for _, cmd := range addRem.addedCommands {
-				_, ok := pkg.Functions[cmd]
+				existing, ok := pkg.Functions[cmd]
 				if !ok {
 					pkg.Functions[cmd] = &PackageFunction{
-						Function: *spec.Functions.get(cmd, pkg.API),
-						Required: false,
+						Function:  *spec.Functions.get(cmd, pkg.API),
+						Required:  false,
+						Extension: extension.Name,
 					}
+				} else if existing.Extension != "" {
+					existing.OtherExtensions = appendExtension(existing.Extension, existing.OtherExtensions, extension.Name)
 				}
 			}
 			for _, enum := range addRem.addedEnums {
-				pkg.Enums[enum] = spec.Enums.get(enum, pkg.API)
+				existing, ok := pkg.Enums[enum]
+				if !ok {
+					e := *spec.Enums.get(enum, pkg.API)
+					e.Extension = extension.Name
+					pkg.Enums[enum] = &e
+				} else if existing.Extension != "" {
+					existing.OtherExtensions = appendExtension(existing.Extension, existing.OtherExtensions, extension.Name)
+				}
+			}
+			for _, name := range addRem.addedTypes {
+				spec.selectRequiredType(name, pkg, Version{}, extension.Name)
 			}
 		}
 	}
 
+	if pkgSpec.AliasFallback {
+		spec.addAliasFallbacks(pkg, pkgSpec)
+	}
+
 	// Add the types necessary to declare the functions
 	for _, fn := range pkg.Functions {
 		spec.Typedefs.selectRequired(fn.Function.Return.Name, pkg.API, pkg.Typedefs)
@@ -640,14 +1712,16 @@ func (spec *Specification) ToPackage(pkgSpec *PackageSpec) *Package {
 			spec.Typedefs.selectRequired(param.Type.Name, pkg.API, pkg.Typedefs)
 		}
 	}
-	typedefCount := 0
-	for _, typedef := range pkg.Typedefs {
-		if typedef != nil {
-			pkg.Typedefs[typedefCount] = typedef
-			typedefCount++
-		}
+	pkg.Typedefs = spec.Typedefs.sortRequired(pkg.Typedefs, pkg.API)
+
+	sort.Slice(pkg.Extensions, func(i, j int) bool {
+		return pkg.Extensions[i].Name < pkg.Extensions[j].Name
+	})
+
+	if pkg.IsWebGL() {
+		pkg.restrictToWebGL()
 	}
-	pkg.Typedefs = pkg.Typedefs[:typedefCount]
+	pkg.typeErrorQuery()
 
 	return pkg
 }
@@ -1,6 +1,10 @@
 package main
 
-import "testing"
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
 
 func TestParseSignature(t *testing.T) {
 	tt := []struct {
@@ -42,6 +46,7 @@ func TestParseSignature(t *testing.T) {
 				Name:         "GLuint",
 				PointerLevel: 1,
 				CDefinition:  "GLuint *",
+				ArraySize:    2,
 			},
 		},
 		{
@@ -78,3 +83,148 @@ func TestParseSignature(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCallback(t *testing.T) {
+	tt := []struct {
+		input          string
+		expectedGoName string
+		expectedParams []string
+		userParam      int
+	}{
+		{
+			input:          "typedef void (APIENTRY *GLDEBUGPROC)(GLenum source,GLenum type,GLuint id,GLenum severity,GLsizei length,const GLchar *message,const void *userParam);",
+			expectedGoName: "DebugProc",
+			expectedParams: []string{"source", "type", "id", "severity", "length", "message", "userParam"},
+			userParam:      6,
+		},
+		{
+			input:          "typedef void (APIENTRY *GLDEBUGPROCAMD)(GLuint id,GLenum category,GLenum severity,GLsizei length,const GLchar *message,void *userParam);",
+			expectedGoName: "DebugProcAMD",
+			expectedParams: []string{"id", "category", "severity", "length", "message", "userParam"},
+			userParam:      5,
+		},
+		{
+			input:          "typedef EGLsizeiANDROID (*EGLGetBlobFuncANDROID) (const void *key, EGLsizeiANDROID keySize, void *value, EGLsizeiANDROID valueSize);",
+			expectedGoName: "GetBlobFuncANDROID",
+			expectedParams: []string{"key", "keySize", "value", "valueSize"},
+			userParam:      -1,
+		},
+		{
+			input:          "typedef void (APIENTRY *GLVULKANPROCNV)(void);",
+			expectedGoName: "VulkanProcNV",
+			userParam:      -1,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.expectedGoName, func(t *testing.T) {
+			callback, err := parseCallback(tc.input)
+			if err != nil || callback == nil {
+				t.Fatalf("parseCallback returned %v, %v", callback, err)
+			}
+			if callback.GoName() != tc.expectedGoName {
+				t.Errorf("Go name [%s] does not match expected [%s]", callback.GoName(), tc.expectedGoName)
+			}
+			var params []string
+			for _, p := range callback.Parameters {
+				params = append(params, p.Name)
+			}
+			if strings.Join(params, ",") != strings.Join(tc.expectedParams, ",") {
+				t.Errorf("parameters %v do not match expected %v", params, tc.expectedParams)
+			}
+			if callback.UserParam() != tc.userParam {
+				t.Errorf("user parameter %d does not match expected %d", callback.UserParam(), tc.userParam)
+			}
+		})
+	}
+
+	if callback, err := parseCallback("typedef unsigned int GLenum;"); callback != nil || err != nil {
+		t.Errorf("parseCallback parsed a non-callback typedef: %v, %v", callback, err)
+	}
+}
+
+func TestExtensionShouldInclude(t *testing.T) {
+	tt := []struct {
+		supported string
+		pkgSpec   PackageSpec
+		expected  bool
+	}{
+		{"gles1|gles2|glsc2", PackageSpec{API: "glsc2"}, true},
+		{"gl|glcore|gles2|glsc2", PackageSpec{API: "glsc2"}, true},
+		{"gl|glcore|gles2", PackageSpec{API: "glsc2"}, false},
+		{"gles2", PackageSpec{API: "glsc2"}, false},
+		{"gl|glcore", PackageSpec{API: "gl", Profile: "core"}, true},
+		{"gl", PackageSpec{API: "gl", Profile: "core"}, false},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.supported+"/"+tc.pkgSpec.API, func(t *testing.T) {
+			extension := SpecificationExtension{APIRegexp: regexp.MustCompile("^(" + tc.supported + ")$")}
+			if included := extension.shouldInclude(&tc.pkgSpec); included != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, included)
+			}
+		})
+	}
+}
+
+func TestParseEnumValue(t *testing.T) {
+	tt := []struct {
+		value    string
+		bitpos   string
+		expected string
+	}{
+		{"0x1F", "", "0x1F"},
+		{"-7", "", "-7"},
+		{"", "4", "0x10"},
+		{"", "33", "0x200000000"},
+		{"(~0U)", "", "0xFFFFFFFF"},
+		{"(~1U)", "", "0xFFFFFFFE"},
+		{"(~0ULL)", "", "0xFFFFFFFFFFFFFFFF"},
+		{"256U", "", "256"},
+		{"1000.0F", "", "1000.0"},
+		{"\"VK_KHR_surface\"", "", "\"VK_KHR_surface\""},
+		{"EGL_CAST(EGLDisplay,0)", "", "Display(0)"},
+		{"EGL_CAST(EGLint,-1)", "", "int32(-1)"},
+	}
+
+	for _, tc := range tt {
+		value, err := parseEnumValue("VK_TEST", tc.value, tc.bitpos)
+		if err != nil {
+			t.Errorf("parseEnumValue(%q, %q) failed: %v", tc.value, tc.bitpos, err)
+		} else if value != tc.expected {
+			t.Errorf("parseEnumValue(%q, %q) = %q, expected %q", tc.value, tc.bitpos, value, tc.expected)
+		}
+	}
+}
+
+func TestSatisfies(t *testing.T) {
+	spec := &Specification{
+		Features: []SpecificationFeature{
+			{API: "vulkan", Name: "VK_VERSION_1_0", Version: Version{1, 0}},
+			{API: "vulkan", Name: "VK_VERSION_1_1", Version: Version{1, 1}},
+		},
+	}
+	extensions := map[string]bool{"VK_KHR_surface": true}
+	tt := []struct {
+		depends  string
+		expected bool
+	}{
+		{"", true},
+		{"VK_VERSION_1_0", true},
+		{"VK_VERSION_1_1", false},
+		{"VK_KHR_swapchain", false},
+		{"VK_VERSION_1_1,VK_KHR_surface", true},
+		{"VK_VERSION_1_0+VK_KHR_swapchain", false},
+		{"VK_KHR_surface+(VK_VERSION_1_1,VK_KHR_swapchain)", false},
+		{"(VK_VERSION_1_1,VK_KHR_surface)+VK_VERSION_1_0", true},
+	}
+
+	pkgSpec := &PackageSpec{API: "vulkan", Version: Version{1, 0}}
+	for _, tc := range tt {
+		if satisfied := spec.satisfies(tc.depends, pkgSpec, extensions); satisfied != tc.expected {
+			t.Errorf("satisfies(%q) = %v, expected %v", tc.depends, satisfied, tc.expected)
+		}
+	}
+}
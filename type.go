@@ -11,12 +11,47 @@ type Type struct {
 	PointerLevel int    // Number of levels of declared indirection to the type
 	CDefinition  string // Raw C definition
 	Cast         string // Raw C cast in case conversion is necessary
+	ArraySize    int    // Size of the array the outermost pointer refers to, or 0
+	Underlying   string // Name of the type Name ultimately aliases, if any
+	Category     string // Registry category of the type Name ultimately aliases, e.g., "struct"
+	WindowSystem string // Window system API whose types include Name, e.g., "egl", if any
+	Named        string // Go type the package defines for the type, e.g., "Error", if any
+
+	Callback *Callback // Signature of the function pointer type, if any
 }
 
 // A Typedef describes a C typedef statement.
 type Typedef struct {
-	Name        string // Name of the defined type (or included types)
-	CDefinition string // Raw C definition
+	Name        string    // Name of the defined type (or included types)
+	CDefinition string    // Raw C definition
+	Callback    *Callback // Signature of function pointer types, if any
+	Base        string    // Name of the aliased type, for typedefs such as "typedef cl_uint cl_bool;"
+	Category    string    // Registry category, e.g., "struct", "union", "handle", or "enum"
+	Members     []Member  // Members of structs and unions
+}
+
+// A Member describes a member of a struct or union.
+type Member struct {
+	Name string
+	Type Type
+	Dims []int // Sizes of the array dimensions, if the member is an array
+	Bits int   // Width of the bit-field, if the member is one
+}
+
+// A StructField describes a field of the Go mirror of a struct.
+type StructField struct {
+	Name    string // Go name of the field
+	GoType  string // Go type of the field
+	Comment string // Bit-fields packed into the field, if any
+}
+
+// A Callback describes the signature of a function pointer typedef, e.g.,
+// GLDEBUGPROC.
+type Callback struct {
+	Name       string // Name of the typedef
+	Parameters []Parameter
+	Return     Type
+	Convention string // Calling convention of the function, APIENTRY or CL_CALLBACK, if any
 }
 
 func (t Type) String() string {
@@ -25,7 +60,7 @@ func (t Type) String() string {
 
 // IsVoid indicates whether this type is the void pseudo-type.
 func (t Type) IsVoid() bool {
-	return (t.Name == "void" || t.Name == "GLvoid") && t.PointerLevel == 0
+	return (t.Name == "void" || t.Name == "GLvoid" || t.Name == "VOID") && t.PointerLevel == 0
 }
 
 // IsDebugProc indicates whether this type is a debug callback function pointer.
@@ -33,6 +68,21 @@ func (t Type) IsDebugProc() bool {
 	return t.Name == "GLDEBUGPROC" || t.Name == "GLDEBUGPROCARB" || t.Name == "GLDEBUGPROCKHR"
 }
 
+// IsCString indicates whether this type is a constant, null-terminated string.
+func (t Type) IsCString() bool {
+	switch t.Name {
+	case "char", "GLchar", "GLcharARB":
+		return t.PointerLevel == 1 && strings.HasPrefix(strings.TrimSpace(t.CDefinition), "const")
+	}
+	return false
+}
+
+// IsCallback indicates whether this type is a function pointer the Go
+// bindings can call back into.
+func (t Type) IsCallback() bool {
+	return t.Callback != nil && t.PointerLevel == 0
+}
+
 // CType returns the C definition of the type.
 func (t Type) CType() string {
 	return t.CDefinition
@@ -40,14 +90,45 @@ func (t Type) CType() string {
 
 // GoCType returns the Go definition of the C type.
 func (t Type) GoCType() string {
+	if (t.Name == "void" || t.Name == "GLvoid") && t.PointerLevel >= 1 {
+		return strings.Repeat("*", t.PointerLevel-1) + "unsafe.Pointer"
+	}
 	if strings.HasPrefix(t.Name, "struct ") {
 		return t.pointers() + "C.struct_" + strings.TrimPrefix(t.Name, "struct ")
 	}
+	for _, match := range structTagRegexp.FindAllStringSubmatch(t.CDefinition, -1) {
+		if match[1] == t.Name {
+			// Parameters such as "struct wl_display *display" refer to the tag
+			return t.pointers() + "C.struct_" + t.Name
+		}
+	}
+	// Multi-word types such as "unsigned int" lose their spaces when parsed
+	switch t.Name {
+	case "unsignedint":
+		return t.pointers() + "C.uint"
+	case "unsignedlong":
+		return t.pointers() + "C.ulong"
+	}
 	return t.pointers() + "C." + t.Name
 }
 
 // GoType returns the Go definition of the type.
 func (t Type) GoType() string {
+	if t.Named != "" {
+		return t.pointers() + t.Named
+	}
+	if t.ArraySize > 0 && t.PointerLevel > 0 {
+		// C passes fixed-size arrays as pointers to their first element
+		elem := t
+		elem.PointerLevel, elem.ArraySize = t.PointerLevel-1, 0
+		return fmt.Sprintf("*[%d]%s", t.ArraySize, elem.GoType())
+	}
+	if ws, ok := t.windowSystemType(); ok && ws.goType != "" {
+		if t.isWindowSystemBoolean() {
+			return "bool"
+		}
+		return t.pointers() + ws.goType
+	}
 	switch t.Name {
 	case "GLbyte":
 		return t.pointers() + "int8"
@@ -82,9 +163,9 @@ func (t Type) GoType() string {
 		return t.pointers() + "bool"
 	case "GLenum", "GLbitfield":
 		return t.pointers() + "uint32"
-	case "GLhalf", "GLhalfNV":
-		// Go has no 16-bit floating point type
-		return t.pointers() + "uint16"
+	case "GLhalf", "GLhalfARB", "GLhalfNV":
+		// Go has no 16-bit floating point type, packages define Half instead
+		return t.pointers() + "Half"
 	case "void", "GLvoid":
 		// Type void* could map to either uintptr or unsafe.Pointer but we use the
 		// latter because pointers passed from Go to C need to use Go pointer types
@@ -110,17 +191,82 @@ func (t Type) GoType() string {
 		// GLsync is treated as an opaque, pointer-width type. Additional special
 		// case handling is required for the corresponding typedef, see CTypedef.
 		return t.pointers() + "uintptr"
-	case "GLDEBUGPROC", "GLDEBUGPROCARB", "GLDEBUGPROCKHR":
-		// Special case mapping to the type defined in debug.tmpl
-		return "DebugProc"
 	case "uintptr_t":
 		return t.pointers() + "uintptr"
+	case "intptr_t":
+		// Same as GLintptr
+		return t.pointers() + "int"
+	case "size_t":
+		return t.pointers() + "uint"
+	case "int8_t":
+		return t.pointers() + "int8"
+	case "uint8_t":
+		return t.pointers() + "uint8"
+	case "int16_t":
+		return t.pointers() + "int16"
+	case "uint16_t":
+		return t.pointers() + "uint16"
+	case "int32_t":
+		return t.pointers() + "int32"
+	case "uint32_t":
+		return t.pointers() + "uint32"
+	case "int64_t":
+		return t.pointers() + "int64"
+	case "uint64_t":
+		return t.pointers() + "uint64"
+	case "float":
+		return t.pointers() + "float32"
+	case "double":
+		return t.pointers() + "float64"
+	case "cl_char":
+		return t.pointers() + "int8"
+	case "cl_uchar":
+		return t.pointers() + "uint8"
+	case "cl_short":
+		return t.pointers() + "int16"
+	case "cl_ushort":
+		return t.pointers() + "uint16"
+	case "cl_int":
+		return t.pointers() + "int32"
+	case "cl_uint":
+		return t.pointers() + "uint32"
+	case "cl_long":
+		return t.pointers() + "int64"
+	case "cl_ulong":
+		return t.pointers() + "uint64"
+	case "cl_half":
+		return t.pointers() + "Half"
+	case "cl_float":
+		return t.pointers() + "float32"
+	case "cl_double":
+		return t.pointers() + "float64"
+	}
+	if t.Underlying != "" {
+		// Aliases such as cl_device_type map like the type they alias
+		underlying := t
+		underlying.Name, underlying.Underlying = t.Underlying, ""
+		return underlying.GoType()
+	}
+	switch t.Category {
+	case "struct", "union":
+		// Structs and unions map to the Go mirrors defined in structs.tmpl
+		return t.pointers() + mirrorName(strings.TrimPrefix(t.Name, "struct "))
+	case "handle":
+		// Dispatchable handles are opaque pointers, other handles alias uint64_t
+		return t.pointers() + "unsafe.Pointer"
+	}
+	if t.IsCallback() {
+		// Function pointers map to the Go types defined in callbacks.tmpl
+		return t.Callback.GoName()
 	}
 	return "unsafe.Pointer"
 }
 
 // ConvertGoToC returns an expression that converts a variable from the Go type to the C type.
 func (t Type) ConvertGoToC(name string) string {
+	if t.isWindowSystemBoolean() {
+		return fmt.Sprintf("(%s)(boolToInt(%s))", t.GoCType(), name)
+	}
 	switch t.Name {
 	case "GLboolean":
 		if t.PointerLevel == 0 {
@@ -128,8 +274,13 @@ func (t Type) ConvertGoToC(name string) string {
 		}
 	case "void", "GLvoid":
 		return name
-	case "GLDEBUGPROC", "GLDEBUGPROCARB", "GLDEBUGPROCKHR":
-		return fmt.Sprintf("(C.%s)(unsafe.Pointer(&%s))", t.Name, name)
+	}
+	if t.isWindowSystemPointer() {
+		return fmt.Sprintf("(%s)(unsafe.Pointer(%s))", t.GoCType(), name)
+	}
+	if t.PointerLevel == 0 && (t.Category == "struct" || t.Category == "union") {
+		// Go mirrors are laid out like the C types they mirror
+		return fmt.Sprintf("*(*%s)(unsafe.Pointer(&%s))", t.GoCType(), name)
 	}
 	if t.PointerLevel >= 1 && t.GoType() != "unsafe.Pointer" {
 		return fmt.Sprintf("(%s)(unsafe.Pointer(%s))", t.GoCType(), name)
@@ -139,9 +290,18 @@ func (t Type) ConvertGoToC(name string) string {
 
 // ConvertCToGo converts from the C type to the Go type.
 func (t Type) ConvertCToGo(name string) string {
+	if t.isWindowSystemBoolean() {
+		return fmt.Sprintf("%s != 0", name)
+	}
 	if t.Name == "GLboolean" {
 		return fmt.Sprintf("%s == TRUE", name)
 	}
+	if t.PointerLevel == 0 && (t.Category == "struct" || t.Category == "union") {
+		return fmt.Sprintf("*(*%s)(unsafe.Pointer(&%s))", t.GoType(), name)
+	}
+	if t.isWindowSystemPointer() {
+		return fmt.Sprintf("(%s)(unsafe.Pointer(%s))", t.GoType(), name)
+	}
 	return fmt.Sprintf("(%s)(%s)", t.GoType(), name)
 }
 
@@ -149,6 +309,90 @@ func (t Type) pointers() string {
 	return strings.Repeat("*", t.PointerLevel)
 }
 
+// GoName returns the name of the Go type mirroring a struct or union.
+func (t *Typedef) GoName() string {
+	return mirrorName(t.Name)
+}
+
+// GoBase returns the name of the Go type aliased by the typedef.
+func (t *Typedef) GoBase() string {
+	return mirrorName(t.Base)
+}
+
+// mirrorName returns the exported Go name mirroring a C name, e.g.,
+// ImageFormat for cl_image_format.
+func mirrorName(name string) string {
+	var goName string
+	for _, word := range strings.Split(TrimAPIPrefix(name), "_") {
+		if word != "" {
+			goName += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return goName
+}
+
+// Fields returns the fields of the Go mirror of a struct. Go has no
+// bit-fields, so runs of them sharing a storage unit are packed into a single
+// field of the storage type.
+func (t *Typedef) Fields() []StructField {
+	var fields []StructField
+	for i := 0; i < len(t.Members); i++ {
+		member := t.Members[i]
+		field := StructField{Name: member.GoName(), GoType: member.GoType()}
+		if member.Bits > 0 {
+			names := []string{member.GoName()}
+			bits := []string{fmt.Sprintf("%s:%d", member.Name, member.Bits)}
+			used := member.Bits
+			for ; i+1 < len(t.Members); i++ {
+				next := t.Members[i+1]
+				if next.Bits == 0 || used+next.Bits > goTypeBits[field.GoType] {
+					break
+				}
+				used += next.Bits
+				names = append(names, next.GoName())
+				bits = append(bits, fmt.Sprintf("%s:%d", next.Name, next.Bits))
+			}
+			field.Name = strings.Join(names, "And")
+			field.Comment = strings.Join(bits, ", ")
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// goTypeBits maps the Go types storing bit-fields to their width.
+var goTypeBits = map[string]int{
+	"int8": 8, "uint8": 8, "int16": 16, "uint16": 16,
+	"int32": 32, "uint32": 32, "int64": 64, "uint64": 64,
+}
+
+// GoName returns the Go name of the struct field mirroring the member.
+func (m Member) GoName() string {
+	return mirrorName(m.Name)
+}
+
+// GoType returns the Go type of the struct field mirroring the member.
+func (m Member) GoType() string {
+	goType := m.Type.GoType()
+	switch {
+	case m.Type.Name == "char":
+		// Strings are stored as bytes, as for GLchar
+		goType = m.Type.pointers() + "uint8"
+	case m.Type.isWindowSystemBoolean():
+		// Booleans such as Bool keep their integer type to preserve the layout
+		ws, _ := m.Type.windowSystemType()
+		goType = ws.goType
+	case goType == "unsafe.Pointer" && m.Type.PointerLevel == 0 && m.Type.Category != "handle" && m.Type.Category != "funcpointer":
+		// Types unknown to Go, such as those of window systems, keep their
+		// C type to preserve the layout
+		goType = m.Type.GoCType()
+	}
+	for i := len(m.Dims) - 1; i >= 0; i-- {
+		goType = fmt.Sprintf("[%d]%s", m.Dims[i], goType)
+	}
+	return goType
+}
+
 // CTypedef returns the C definition of the typedef.
 func (t Typedef) CTypedef() string {
 	// GLsync is defined as an opaque struct pointer, but some OpenGL drivers
@@ -158,5 +402,114 @@ func (t Typedef) CTypedef() string {
 	if strings.Contains(t.CDefinition, "GLsync") {
 		return "typedef uintptr_t GLsync;"
 	}
+	// The OpenCL registry leaves the scalar types to CL/cl_platform.h. Define
+	// them directly so that packages build without the OpenCL headers.
+	if t.Name == "CL/cl_platform.h" {
+		return clPlatformTypedefs
+	}
+	// Likewise Vulkan leaves the scalar types and calling conventions to
+	// vk_platform.h.
+	if t.Name == "vk_platform" {
+		return vkPlatformTypedefs
+	}
+	// Non-dispatchable handles are pointers on 64-bit platforms and 64-bit
+	// integers otherwise. Define them as integers everywhere, which is ABI
+	// compatible, so that they map to uint64 in Go.
+	if t.Name == "VK_USE_64_BIT_PTR_DEFINES" {
+		return "#define VK_USE_64_BIT_PTR_DEFINES 0"
+	}
 	return t.CDefinition
 }
+
+const clPlatformTypedefs = `#include <stddef.h>
+#include <stdint.h>
+#if defined(_WIN32)
+#define CL_CALLBACK __stdcall
+#else
+#define CL_CALLBACK
+#endif
+typedef int8_t cl_char;
+typedef uint8_t cl_uchar;
+typedef int16_t cl_short;
+typedef uint16_t cl_ushort;
+typedef int32_t cl_int;
+typedef uint32_t cl_uint;
+typedef int64_t cl_long;
+typedef uint64_t cl_ulong;
+typedef uint16_t cl_half;
+typedef float cl_float;
+typedef double cl_double;`
+
+const vkPlatformTypedefs = `#include <stddef.h>
+#include <stdint.h>
+#if defined(_WIN32)
+#define VKAPI_ATTR
+#define VKAPI_CALL __stdcall
+#define VKAPI_PTR VKAPI_CALL
+#else
+#define VKAPI_ATTR
+#define VKAPI_CALL
+#define VKAPI_PTR
+#endif`
+
+// CTypedef returns the C definition of the function pointer type.
+func (c *Callback) CTypedef() string {
+	params := make([]string, len(c.Parameters))
+	for i, p := range c.Parameters {
+		params[i] = strings.TrimSpace(p.Type.CDefinition) + " " + p.Name
+	}
+	convention := ""
+	if c.Convention != "" {
+		convention = c.Convention + " "
+	}
+	return fmt.Sprintf("typedef %s (%s*%s)(%s);", strings.TrimSpace(c.Return.CDefinition), convention, c.Name, strings.Join(params, ", "))
+}
+
+// GoName returns the name of the Go type of the callback. The debug callbacks
+// of the core, ARB and KHR variants share the DebugProc type.
+func (c *Callback) GoName() string {
+	switch c.Name {
+	case "GLDEBUGPROC", "GLDEBUGPROCARB", "GLDEBUGPROCKHR":
+		return "DebugProc"
+	}
+	if i := strings.Index(c.Name, "_"); i > 0 && strings.HasPrefix(c.Name, "cl") {
+		// Callbacks declared inline by OpenCL commands are named after the
+		// command and parameter, e.g., clCreateContext_pfn_notify becomes
+		// CreateContextNotify
+		name := TrimAPIPrefix(c.Name[:i])
+		for _, word := range strings.Split(strings.TrimPrefix(c.Name[i+1:], "pfn_"), "_") {
+			if word != "" {
+				name += strings.ToUpper(word[:1]) + word[1:]
+			}
+		}
+		return name
+	}
+	name := c.Name
+	for _, prefix := range []string{"EGL", "GLX", "WGL", "GL"} {
+		if strings.HasPrefix(name, prefix) {
+			name = strings.TrimPrefix(name, prefix)
+			break
+		}
+	}
+	// Upper case names like DEBUGPROCAMD become DebugProcAMD
+	if i := strings.Index(name, "PROC"); i > 0 && name == strings.ToUpper(name) {
+		name = name[:1] + strings.ToLower(name[1:i]) + "Proc" + name[i+len("PROC"):]
+	}
+	return name
+}
+
+// UserParam returns the index of the parameter passing user data through to
+// the callback, or -1 if there is none.
+func (c *Callback) UserParam() int {
+	for i, p := range c.Parameters {
+		if p.IsUserParam() {
+			return i
+		}
+	}
+	return -1
+}
+
+// HasUserParam returns whether the callback receives user data.
+func (c *Callback) HasUserParam() bool {
+	return c.UserParam() >= 0
+}
@@ -16,6 +16,51 @@ func TestGoType(t *testing.T) {
 			},
 			expected: "*uintptr",
 		},
+		{
+			in: Type{
+				Name:         "GLuint",
+				PointerLevel: 1,
+				CDefinition:  "GLuint *",
+				ArraySize:    2,
+			},
+			expected: "*[2]uint32",
+		},
+		{
+			in: Type{
+				Name:         "GLfloat",
+				PointerLevel: 2,
+				CDefinition:  "GLfloat **",
+				ArraySize:    4,
+			},
+			expected: "*[4]*float32",
+		},
+		{
+			in: Type{
+				Name:         "VkInstanceCreateInfo",
+				PointerLevel: 1,
+				CDefinition:  "const VkInstanceCreateInfo*",
+				Category:     "struct",
+			},
+			expected: "*InstanceCreateInfo",
+		},
+		{
+			in: Type{
+				Name:         "cl_image_format",
+				PointerLevel: 1,
+				CDefinition:  "const cl_image_format*",
+				Category:     "struct",
+			},
+			expected: "*ImageFormat",
+		},
+		{
+			in: Type{
+				Name:         "VkInstance",
+				PointerLevel: 1,
+				CDefinition:  "VkInstance*",
+				Category:     "handle",
+			},
+			expected: "*unsafe.Pointer",
+		},
 	}
 
 	for _, tc := range tt {
@@ -28,3 +73,53 @@ func TestGoType(t *testing.T) {
 		})
 	}
 }
+
+func TestGoCType(t *testing.T) {
+	tt := []struct {
+		in       Type
+		expected string
+	}{
+		{
+			in: Type{
+				Name:         "wl_display",
+				PointerLevel: 1,
+				CDefinition:  "struct wl_display *",
+			},
+			expected: "*C.struct_wl_display",
+		},
+		{
+			in: Type{
+				Name:         "AHardwareBuffer",
+				PointerLevel: 1,
+				CDefinition:  "const struct AHardwareBuffer *",
+			},
+			expected: "*C.struct_AHardwareBuffer",
+		},
+		{
+			in: Type{
+				Name:         "wl_display",
+				PointerLevel: 1,
+				CDefinition:  "struct wl_display_ext *",
+			},
+			expected: "*C.wl_display",
+		},
+		{
+			in: Type{
+				Name:         "GLuint",
+				PointerLevel: 1,
+				CDefinition:  "GLuint *",
+			},
+			expected: "*C.GLuint",
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.in.String(), func(t *testing.T) {
+			goCType := tc.in.GoCType()
+			if goCType != tc.expected {
+				t.Errorf("expected <%s>, got <%s>", tc.expected, goCType)
+			}
+		})
+	}
+}
@@ -2,7 +2,11 @@ package main
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"unicode"
 )
@@ -10,7 +14,7 @@ import (
 // TrimAPIPrefix removes the API-specific prefix from a spec name.
 // e.g., glTest becomes Test; GLX_TEST becomes TEST; egl0Test stays egl0Test
 func TrimAPIPrefix(name string) string {
-	prefixes := []string{"glX", "wgl", "egl", "gl", "GLX_", "WGL_", "EGL_", "GL_"}
+	prefixes := []string{"glX", "wgl", "egl", "gl", "GLX_", "WGL_", "EGL_", "GL_", "cl_", "cl", "CL_", "vk", "Vk", "VK_"}
 
 	trimmed := name
 	prefix := ""
@@ -109,3 +113,66 @@ func (w *BlankLineStrippingWriter) Write(p []byte) (int, error) {
 		}
 	}
 }
+
+// writeFileAtomic writes data to a temporary file next to filename and then
+// renames it into place, so that readers never observe a partially written
+// file.
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// diffFile returns a unified diff from the current contents of filename to
+// data, treating a missing file as empty.
+func diffFile(filename string, data []byte) string {
+	existing, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return unifiedDiff("/dev/null", filename, nil, data)
+	}
+	return unifiedDiff(filename, filename, existing, data)
+}
+
+// staleFileDiff returns a unified diff removing filename, a file glow would
+// not generate.
+func staleFileDiff(filename string) string {
+	existing, err := ioutil.ReadFile(filename)
+	if err != nil || len(existing) == 0 {
+		return fmt.Sprintf("--- %s\n+++ /dev/null\n", filename)
+	}
+	return unifiedDiff(filename, "/dev/null", existing, nil)
+}
+
+// reservedFileSuffixes are file name suffixes the go tool interprets as
+// implicit build constraints or as marking test files.
+var reservedFileSuffixes = lookupMap(strings.Fields(`
+	test
+	aix android darwin dragonfly freebsd hurd illumos ios js linux nacl netbsd
+	openbsd plan9 solaris wasip1 windows zos
+	386 amd64 amd64p32 arm armbe arm64 arm64be loong64 mips mipsle mips64
+	mips64le mips64p32 mips64p32le ppc ppc64 ppc64le riscv riscv64 s390 s390x
+	sparc sparc64 wasm`))
+
+// goFileName returns name, extended if necessary so that the go tool does not
+// interpret name+".go" as a test or platform-specific file.
+func goFileName(name string) string {
+	parts := strings.Split(name, "_")
+	if len(parts) > 1 && reservedFileSuffixes[parts[len(parts)-1]] {
+		return name + "_gen"
+	}
+	return name
+}
@@ -22,6 +22,12 @@ var trimAPIPrefixTests = []trimAPIPrefixTest{
 	{"GLX_TEST", "TEST"},
 	{"GL_0TEST", "GL_0TEST"},
 	{"gl0Test", "gl0Test"},
+	{"clTest", "Test"},
+	{"CL_TEST", "TEST"},
+	{"cl_khr_test", "khr_test"},
+	{"vkTest", "Test"},
+	{"VkTest", "Test"},
+	{"VK_TEST", "TEST"},
 }
 
 func TestTrimApiPrefix(t *testing.T) {
@@ -107,3 +113,22 @@ func TestBlankLineStrippingWriter2(t *testing.T) {
 			string(b[:50]), string(want[:50]))
 	}
 }
+
+func TestGoFileName(t *testing.T) {
+	tt := []struct {
+		in       string
+		expected string
+	}{
+		{"gl_1_0", "gl_1_0"},
+		{"gl_arb_sync", "gl_arb_sync"},
+		{"gl_ext_depth_bounds_test", "gl_ext_depth_bounds_test_gen"},
+		{"egl_khr_platform_android", "egl_khr_platform_android_gen"},
+		{"gl_foo_arm64", "gl_foo_arm64_gen"},
+		{"linux", "linux"},
+	}
+	for _, tc := range tt {
+		if name := goFileName(tc.in); name != tc.expected {
+			t.Errorf("goFileName(%s): expected %s, got %s", tc.in, tc.expected, name)
+		}
+	}
+}
@@ -33,6 +33,32 @@ func ParseVersion(version string) (Version, error) {
 	return Version{majorNumber, minorNumber}, nil
 }
 
+// ParseVersionRange returns the minimum and maximum Version of a
+// "major.minor-major.minor" version range, e.g., "3.3-4.6". A single version,
+// including "all", has no minimum, i.e., the minimum is the zero Version.
+func ParseVersionRange(versions string) (Version, Version, error) {
+	split := strings.Split(versions, "-")
+	if len(split) == 1 {
+		max, err := ParseVersion(versions)
+		return Version{}, max, err
+	}
+	if len(split) != 2 || split[0] == "all" || split[1] == "all" {
+		return Version{}, Version{}, fmt.Errorf("invalid version range: %s", versions)
+	}
+	min, err := ParseVersion(split[0])
+	if err != nil {
+		return Version{}, Version{}, err
+	}
+	max, err := ParseVersion(split[1])
+	if err != nil {
+		return Version{}, Version{}, err
+	}
+	if min.Compare(max) >= 0 {
+		return Version{}, Version{}, fmt.Errorf("invalid version range: %s does not follow %s", max, min)
+	}
+	return min, max, nil
+}
+
 // Compare compares two versions, returning 1, 0, or -1 if the compared version
 // is before, after, or equal to this version respectively. The "all versions"
 // pseudo-version is equal to all other versions.
@@ -35,6 +35,34 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseVersionRange(t *testing.T) {
+	tt := []struct {
+		in       string
+		min, max Version
+		ok       bool
+	}{
+		{"3.3", Version{}, Version{3, 3}, true},
+		{"all", Version{}, Version{-1, -1}, true},
+		{"3.3-4.6", Version{3, 3}, Version{4, 6}, true},
+		{"4.6-3.3", Version{}, Version{}, false},
+		{"3.3-3.3", Version{}, Version{}, false},
+		{"3.3-all", Version{}, Version{}, false},
+		{"all-4.6", Version{}, Version{}, false},
+		{"3.3-", Version{}, Version{}, false},
+		{"3.3-4.0-4.6", Version{}, Version{}, false},
+	}
+	for _, tc := range tt {
+		min, max, err := ParseVersionRange(tc.in)
+		if (err == nil) != tc.ok {
+			t.Errorf("ParseVersionRange(%q) error = %v", tc.in, err)
+			continue
+		}
+		if tc.ok && (min != tc.min || max != tc.max) {
+			t.Errorf("ParseVersionRange(%q) = %v, %v, want %v, %v", tc.in, min, max, tc.min, tc.max)
+		}
+	}
+}
+
 func TestCompare(t *testing.T) {
 	v10 := Version{1, 0}
 	v11 := Version{1, 1}
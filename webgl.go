@@ -0,0 +1,451 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// A WebGLCall describes how a function of a package generated with the webgl
+// backend is implemented with a WebGL2RenderingContext.
+type WebGLCall struct {
+	Kind    string   // "call" for a context method, "store" for a method whose result is stored, or "helper" for a package helper
+	Method  string   // Name of the context method or package helper
+	Args    []string // Go expressions of the arguments
+	Result  string   // Go expression (or statement, for "store") converting ret, the result of the method
+	Returns bool     // Whether the function returns a value
+}
+
+// webglFunction overrides the WebGL implementation derived from a function's
+// signature.
+type webglFunction struct {
+	kind   string   // Kind of the call, "call" if empty
+	method string   // Method or helper name, derived from the Go name if empty
+	args   []string // Arguments, derived from the parameters if nil
+	result string   // Result conversion; "%[1]s" refers to the output parameter
+}
+
+// webglFunctions lists the functions whose WebGL implementation cannot be
+// derived from their signature alone.
+var webglFunctions = map[string]webglFunction{
+	"glClearDepthf": {method: "clearDepth"},
+	"glDepthRangef": {method: "depthRange"},
+
+	"glBufferData":   {args: []string{"target", "bufferSource(data, size)", "usage"}},
+	"glPixelStorei":  {kind: "helper", method: "pixelStorei", args: []string{"pname", "param"}},
+	"glReadPixels":   {kind: "helper", method: "readPixels", args: []string{"x", "y", "width", "height", "format", "xtype", "pixels"}},
+	"glShaderSource": {kind: "helper", method: "shaderSource", args: []string{"shader", "count", "xstring", "length"}},
+	"glGetString":    {kind: "helper", method: "getString", args: []string{"name"}},
+	"glGetStringi":   {kind: "helper", method: "getStringi", args: []string{"name", "index"}},
+	"glWaitSync":     {args: []string{"object(uint32(sync))", "flags", "int64(timeout)"}},
+
+	"glGetUniformLocation": {kind: "helper", method: "getUniformLocation", args: []string{"program", "GoStr(name)"}},
+	"glLinkProgram":        {kind: "helper", method: "linkProgram", args: []string{"program"}},
+
+	"glTexParameterfv":     {method: "texParameterf", args: []string{"target", "pname", "*params"}},
+	"glTexParameteriv":     {method: "texParameteri", args: []string{"target", "pname", "*params"}},
+	"glSamplerParameterfv": {method: "samplerParameterf", args: []string{"object(sampler)", "pname", "*param"}},
+	"glSamplerParameteriv": {method: "samplerParameteri", args: []string{"object(sampler)", "pname", "*param"}},
+
+	"glGetBooleanv":                         {kind: "store", method: "getParameter"},
+	"glGetFloatv":                           {kind: "store", method: "getParameter"},
+	"glGetIntegerv":                         {kind: "store", method: "getParameter"},
+	"glGetInteger64v":                       {kind: "store", method: "getParameter"},
+	"glGetIntegeri_v":                       {kind: "store", method: "getIndexedParameter"},
+	"glGetInteger64i_v":                     {kind: "store", method: "getIndexedParameter"},
+	"glGetBufferParameteriv":                {kind: "store", method: "getBufferParameter"},
+	"glGetBufferParameteri64v":              {kind: "store", method: "getBufferParameter"},
+	"glGetFramebufferAttachmentParameteriv": {kind: "store", method: "getFramebufferAttachmentParameter"},
+	"glGetProgramiv":                        {kind: "store", method: "getProgramParameter"},
+	"glGetShaderiv":                         {kind: "store", method: "getShaderParameter"},
+	"glGetQueryiv":                          {kind: "store", method: "getQuery"},
+	"glGetQueryObjectuiv":                   {kind: "store", method: "getQueryParameter"},
+	"glGetRenderbufferParameteriv":          {kind: "store", method: "getRenderbufferParameter"},
+	"glGetSamplerParameterfv":               {kind: "store", method: "getSamplerParameter"},
+	"glGetSamplerParameteriv":               {kind: "store", method: "getSamplerParameter"},
+	"glGetTexParameterfv":                   {kind: "store", method: "getTexParameter"},
+	"glGetTexParameteriv":                   {kind: "store", method: "getTexParameter"},
+	"glGetUniformfv":                        {kind: "store", method: "getUniform"},
+	"glGetUniformiv":                        {kind: "store", method: "getUniform"},
+	"glGetUniformuiv":                       {kind: "store", method: "getUniform"},
+	"glGetVertexAttribfv":                   {kind: "store", method: "getVertexAttrib"},
+	"glGetVertexAttribiv":                   {kind: "store", method: "getVertexAttrib"},
+	"glGetVertexAttribIiv":                  {kind: "store", method: "getVertexAttrib"},
+	"glGetVertexAttribIuiv":                 {kind: "store", method: "getVertexAttrib"},
+	"glGetActiveUniformBlockiv":             {kind: "store", method: "getActiveUniformBlockParameter"},
+	"glGetActiveUniformsiv":                 {kind: "store", method: "getActiveUniforms"},
+	"glGetUniformIndices":                   {kind: "store", method: "getUniformIndices"},
+
+	"glGetInternalformativ": {kind: "store", method: "getInternalformatParameter", args: []string{"target", "internalformat", "pname"}, result: "storeInt32s(%[1]s, int(count), ret)"},
+	"glGetSynciv":           {kind: "store", method: "getSyncParameter", args: []string{"object(uint32(sync))", "pname"}, result: "storeCount(length, storeInt32s(%[1]s, int(count), ret))"},
+	"glGetAttachedShaders":  {kind: "store", args: []string{"object(program)"}, result: "storeCount(count, storeUint32s(%[1]s, int(maxCount), ret))"},
+
+	"glGetShaderPrecisionFormat": {kind: "store", args: []string{"shadertype", "precisiontype"}, result: "storePrecisionFormat(ret, xrange, precision)"},
+	"glGetVertexAttribPointerv":  {kind: "store", method: "getVertexAttribOffset", args: []string{"index", "pname"}, result: "storeOffset(unsafe.Pointer(%[1]s), ret)"},
+
+	"glGetProgramInfoLog":           {kind: "store", args: []string{"object(program)"}, result: "storeString(ret, bufSize, length, %[1]s)"},
+	"glGetShaderInfoLog":            {kind: "store", args: []string{"object(shader)"}, result: "storeString(ret, bufSize, length, %[1]s)"},
+	"glGetShaderSource":             {kind: "store", args: []string{"object(shader)"}, result: "storeString(ret, bufSize, length, %[1]s)"},
+	"glGetActiveUniformBlockName":   {kind: "store", args: []string{"object(program)", "uniformBlockIndex"}, result: "storeString(ret, bufSize, length, %[1]s)"},
+	"glGetActiveAttrib":             {kind: "store", args: []string{"object(program)", "index"}, result: "storeActiveInfo(ret, bufSize, length, size, xtype, %[1]s)"},
+	"glGetActiveUniform":            {kind: "store", args: []string{"object(program)", "index"}, result: "storeActiveInfo(ret, bufSize, length, size, xtype, %[1]s)"},
+	"glGetTransformFeedbackVarying": {kind: "store", args: []string{"object(program)", "index"}, result: "storeActiveInfo(ret, bufSize, length, size, xtype, %[1]s)"},
+}
+
+// webglUnsupportedFunctions lists the OpenGL ES 3.0 functions without a WebGL
+// counterpart, mostly because WebGL cannot expose client memory mappings or
+// binary shaders and programs.
+var webglUnsupportedFunctions = map[string]bool{
+	"glFlushMappedBufferRange": true,
+	"glGetBufferPointerv":      true,
+	"glGetProgramBinary":       true,
+	"glMapBufferRange":         true,
+	"glProgramBinary":          true,
+	"glProgramParameteri":      true,
+	"glReleaseShaderCompiler":  true,
+	"glShaderBinary":           true,
+	"glUnmapBuffer":            true,
+}
+
+// webglUnsupportedEnums lists the OpenGL ES 3.0 enums WebGL 2 does not define,
+// see https://registry.khronos.org/webgl/specs/latest/2.0/#5.
+var webglUnsupportedEnums = map[string]bool{
+	"GL_FIXED":                                     true,
+	"GL_SHADER_COMPILER":                           true,
+	"GL_SHADER_BINARY_FORMATS":                     true,
+	"GL_NUM_SHADER_BINARY_FORMATS":                 true,
+	"GL_NUM_COMPRESSED_TEXTURE_FORMATS":            true,
+	"GL_NUM_EXTENSIONS":                            true,
+	"GL_INFO_LOG_LENGTH":                           true,
+	"GL_SHADER_SOURCE_LENGTH":                      true,
+	"GL_ACTIVE_ATTRIBUTE_MAX_LENGTH":               true,
+	"GL_ACTIVE_UNIFORM_MAX_LENGTH":                 true,
+	"GL_ACTIVE_UNIFORM_BLOCK_MAX_NAME_LENGTH":      true,
+	"GL_TRANSFORM_FEEDBACK_VARYING_MAX_LENGTH":     true,
+	"GL_UNIFORM_NAME_LENGTH":                       true,
+	"GL_UNIFORM_BLOCK_NAME_LENGTH":                 true,
+	"GL_BUFFER_ACCESS_FLAGS":                       true,
+	"GL_BUFFER_MAPPED":                             true,
+	"GL_BUFFER_MAP_LENGTH":                         true,
+	"GL_BUFFER_MAP_OFFSET":                         true,
+	"GL_BUFFER_MAP_POINTER":                        true,
+	"GL_MAP_READ_BIT":                              true,
+	"GL_MAP_WRITE_BIT":                             true,
+	"GL_MAP_INVALIDATE_RANGE_BIT":                  true,
+	"GL_MAP_INVALIDATE_BUFFER_BIT":                 true,
+	"GL_MAP_FLUSH_EXPLICIT_BIT":                    true,
+	"GL_MAP_UNSYNCHRONIZED_BIT":                    true,
+	"GL_PROGRAM_BINARY_RETRIEVABLE_HINT":           true,
+	"GL_PROGRAM_BINARY_LENGTH":                     true,
+	"GL_NUM_PROGRAM_BINARY_FORMATS":                true,
+	"GL_PROGRAM_BINARY_FORMATS":                    true,
+	"GL_PRIMITIVE_RESTART_FIXED_INDEX":             true,
+	"GL_COMPRESSED_R11_EAC":                        true,
+	"GL_COMPRESSED_SIGNED_R11_EAC":                 true,
+	"GL_COMPRESSED_RG11_EAC":                       true,
+	"GL_COMPRESSED_SIGNED_RG11_EAC":                true,
+	"GL_COMPRESSED_RGB8_ETC2":                      true,
+	"GL_COMPRESSED_SRGB8_ETC2":                     true,
+	"GL_COMPRESSED_RGB8_PUNCHTHROUGH_ALPHA1_ETC2":  true,
+	"GL_COMPRESSED_SRGB8_PUNCHTHROUGH_ALPHA1_ETC2": true,
+	"GL_COMPRESSED_RGBA8_ETC2_EAC":                 true,
+	"GL_COMPRESSED_SRGB8_ALPHA8_ETC2_EAC":          true,
+}
+
+// webglObjectParams lists the names of the GLuint parameters naming objects,
+// which WebGL refers to by JavaScript values.
+var webglObjectParams = map[string]bool{
+	"array":        true,
+	"buffer":       true,
+	"framebuffer":  true,
+	"id":           true,
+	"program":      true,
+	"renderbuffer": true,
+	"sampler":      true,
+	"shader":       true,
+	"texture":      true,
+}
+
+// webglCountParams lists the names of the parameters counting the elements of
+// the array parameter following them, which WebGL infers from the array.
+var webglCountParams = map[string]bool{
+	"count":          true,
+	"n":              true,
+	"numAttachments": true,
+	"uniformCount":   true,
+}
+
+var (
+	webglUniformRegexp      = regexp.MustCompile(`^glUniform(Matrix)?([1-4])(x([2-4]))?(f|i|ui)v$`)
+	webglVertexAttribRegexp = regexp.MustCompile(`^glVertexAttribI?([1-4])(f|i|ui)v$`)
+)
+
+// restrictToWebGL removes the extensions, functions, and enums WebGL 2 lacks.
+// Functions without a WebGL counterpart are kept, see WebGL.
+func (pkg *Package) restrictToWebGL() {
+	pkg.Extensions = nil
+	for name, fn := range pkg.Functions {
+		if fn.Extension != "" {
+			delete(pkg.Functions, name)
+		}
+	}
+	for name, enum := range pkg.Enums {
+		if enum.Extension != "" || webglUnsupportedEnums[name] {
+			delete(pkg.Enums, name)
+		}
+	}
+}
+
+// IsWebGL returns whether the package is generated with the webgl backend.
+func (pkg *Package) IsWebGL() bool {
+	return pkg.Backend == "webgl"
+}
+
+// WebGL returns the WebGL implementation of the function, or nil if WebGL has
+// no counterpart.
+func (f *PackageFunction) WebGL() *WebGLCall {
+	return webglCall(f.Name, f.GoName, f.Parameters, f.Return)
+}
+
+// WebGLOverload returns the WebGL implementation of an overload of the
+// function, or nil if WebGL has no counterpart.
+func (f *PackageFunction) WebGLOverload(o Overload) *WebGLCall {
+	return webglCall(f.Name, f.GoName, o.Parameters, o.Return)
+}
+
+func webglCall(name, goName string, params []Parameter, ret Type) *WebGLCall {
+	if webglUnsupportedFunctions[name] {
+		return nil
+	}
+	override := webglFunctions[name]
+	call := &WebGLCall{
+		Kind:    override.kind,
+		Method:  override.method,
+		Returns: !ret.IsVoid(),
+	}
+	if call.Kind == "" {
+		call.Kind = "call"
+	}
+	if call.Method == "" {
+		call.Method = strings.ToLower(goName[:1]) + goName[1:]
+	}
+
+	switch {
+	case strings.HasPrefix(name, "glGen") && name != "glGenerateMipmap":
+		// Gen and Delete functions create and delete one object at a time
+		call.Kind, call.Method = "helper", "genObjects"
+		call.Args = []string{webglObjectMethod("create", goName[len("Gen"):]), params[0].GoName(), params[1].GoName()}
+		return call
+	case strings.HasPrefix(name, "glDelete") && len(params) == 2:
+		call.Kind, call.Method = "helper", "deleteObjects"
+		call.Args = []string{webglObjectMethod("delete", goName[len("Delete"):]), params[0].GoName(), params[1].GoName()}
+		return call
+	case strings.HasPrefix(name, "glDelete"):
+		arg := params[0].GoName()
+		if params[0].Type.Name == "GLsync" {
+			arg = "uint32(" + arg + ")"
+		}
+		call.Args = []string{fmt.Sprintf("%q", call.Method), arg}
+		call.Kind, call.Method = "helper", "deleteObject"
+		return call
+	}
+
+	// The output parameter of stores is the last parameter written to
+	out := -1
+	if call.Kind == "store" {
+		for i, p := range params {
+			if p.Type.PointerLevel > 0 && !isConstPointer(p.Type) {
+				out = i
+			}
+		}
+		if out < 0 {
+			return nil
+		}
+	}
+
+	call.Args = override.args
+	if call.Args == nil {
+		args, ok := webglArgs(name, params, call.Kind == "store")
+		if !ok {
+			return nil
+		}
+		call.Args = args
+	}
+
+	switch {
+	case override.result != "":
+		call.Result = strings.Replace(override.result, "%[1]s", params[out].GoName(), -1)
+	case call.Kind == "store":
+		call.Result = fmt.Sprintf("%s(%s, -1, ret)", webglStoreFunc(params[out].Type), params[out].GoName())
+	case call.Kind == "helper" || ret.IsVoid():
+	case ret.Name == "GLsync":
+		call.Result = "uintptr(newObject(ret))"
+	case strings.HasPrefix(name, "glCreate"):
+		call.Result = "newObject(ret)"
+	case ret.Name == "GLboolean":
+		call.Result = "ret.Bool()"
+	case ret.Named != "":
+		call.Result = ret.Named + "(ret.Int())"
+	case ret.GoType() == "uint32" || ret.GoType() == "int32":
+		call.Result = ret.GoType() + "(ret.Int())"
+	default:
+		return nil
+	}
+	return call
+}
+
+// webglArgs converts the parameters of a function to WebGL method arguments.
+// Count parameters are dropped in favor of the length of the JavaScript array
+// they describe, and the output parameters of stores are left out.
+func webglArgs(name string, params []Parameter, store bool) ([]string, bool) {
+	args := make([]string, len(params))
+	dropped := make(map[int]bool)
+	for i, p := range params {
+		arg := p.GoName()
+		goType := p.Type.GoType()
+		switch {
+		case p.Type.Name == "GLsync":
+			arg = "object(uint32(" + arg + "))"
+		case p.Type.Name == "GLuint" && p.Type.PointerLevel == 0 && webglObjectParams[p.Name]:
+			arg = "object(" + arg + ")"
+		case p.Name == "location" && goType == "int32":
+			arg = "uniformLocation(" + arg + ")"
+		case p.Type.PointerLevel > 0 && !isConstPointer(p.Type):
+			if !store {
+				return nil, false
+			}
+			dropped[i] = true
+		case goType == "unsafe.Pointer" && (p.Name == "pointer" || p.Name == "indices"):
+			arg = "int(uintptr(" + arg + "))"
+		case goType == "uintptr":
+			arg = "int(" + arg + ")"
+		case goType == "unsafe.Pointer" && p.Name == "pixels":
+			depth := "1"
+			if hasParam(params, "depth") {
+				depth = "depth"
+			}
+			arg = fmt.Sprintf("pixelData(%s, format, xtype, width, height, %s)", arg, depth)
+		case goType == "unsafe.Pointer" && i > 0 && (params[i-1].Name == "size" || params[i-1].Name == "imageSize"):
+			size := params[i-1].GoName()
+			if params[i-1].Type.GoType() != "int" {
+				size = "int(" + size + ")"
+			}
+			arg = fmt.Sprintf("jsBytes(%s, %s)", arg, size)
+			dropped[i-1] = true
+		case p.Type.IsCString():
+			arg = "GoStr(" + arg + ")"
+		case goType == "**uint8" || goType == "*float32" || goType == "*int32" || goType == "*uint32":
+			length, count := webglArrayLen(name, params, i)
+			if length == "" {
+				return nil, false
+			}
+			if count >= 0 {
+				dropped[count] = true
+			}
+			arg = fmt.Sprintf("%s(%s, %s)", webglArrayFunc(goType), arg, length)
+		case p.Type.PointerLevel > 0:
+			return nil, false
+		}
+		args[i] = arg
+	}
+
+	var kept []string
+	for i, arg := range args {
+		if !dropped[i] {
+			kept = append(kept, arg)
+		}
+	}
+	return kept, true
+}
+
+// webglArrayLen returns a Go expression of the number of elements of the
+// array parameter at index i, and the index of the parameter counting them,
+// or -1 if there is none.
+func webglArrayLen(name string, params []Parameter, i int) (string, int) {
+	count := -1
+	for j := i - 1; j >= 0; j-- {
+		if webglCountParams[params[j].Name] {
+			count = j
+			break
+		}
+	}
+	if m := webglUniformRegexp.FindStringSubmatch(name); m != nil && count >= 0 {
+		size := int(m[2][0] - '0')
+		switch {
+		case m[4] != "":
+			size *= int(m[4][0] - '0')
+		case m[1] != "":
+			size *= size
+		}
+		if size == 1 {
+			return fmt.Sprintf("int(%s)", params[count].GoName()), count
+		}
+		return fmt.Sprintf("int(%s)*%d", params[count].GoName(), size), count
+	}
+	if m := webglVertexAttribRegexp.FindStringSubmatch(name); m != nil {
+		return m[1], -1
+	}
+	if strings.HasPrefix(name, "glClearBuffer") {
+		return "clearBufferLen(buffer)", -1
+	}
+	if count < 0 {
+		return "", -1
+	}
+	return fmt.Sprintf("int(%s)", params[count].GoName()), count
+}
+
+// webglArrayFunc returns the helper converting a Go array of the given type to
+// a JavaScript array.
+func webglArrayFunc(goType string) string {
+	switch goType {
+	case "**uint8":
+		return "jsStrs"
+	case "*float32":
+		return "jsFloat32s"
+	case "*int32":
+		return "jsInt32s"
+	}
+	return "jsUint32s"
+}
+
+// webglStoreFunc returns the helper storing a value returned by WebGL at a
+// pointer of the given type.
+func webglStoreFunc(t Type) string {
+	switch t.GoType() {
+	case "*bool":
+		return "storeBools"
+	case "*float32":
+		return "storeFloat32s"
+	case "*int64":
+		return "storeInt64s"
+	case "*uint32":
+		return "storeUint32s"
+	}
+	return "storeInt32s"
+}
+
+// webglObjectMethod returns the quoted name of the context method creating or
+// deleting a single object of the given plural kind, e.g., "createQuery" for
+// "Queries".
+func webglObjectMethod(verb, kind string) string {
+	switch {
+	case strings.HasSuffix(kind, "ies"):
+		kind = strings.TrimSuffix(kind, "ies") + "y"
+	case strings.HasSuffix(kind, "s"):
+		kind = strings.TrimSuffix(kind, "s")
+	}
+	return fmt.Sprintf("%q", verb+kind)
+}
+
+func isConstPointer(t Type) bool {
+	return strings.HasPrefix(strings.TrimSpace(t.CDefinition), "const")
+}
+
+func hasParam(params []Parameter, name string) bool {
+	for _, p := range params {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
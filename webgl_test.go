@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func newWebGLTestPackage(t *testing.T) *Package {
+	t.Helper()
+	registry, err := readSpecFile(filepath.Join("xml", "spec", "gl.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := NewSpecification(*registry, xmlOverloads{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgSpec := &PackageSpec{
+		API:     "gles2",
+		Version: Version{3, 0},
+		TmplDir: "tmpl",
+		Backend: "webgl",
+	}
+	if !spec.HasPackage(pkgSpec) {
+		t.Fatal("registry cannot generate gles2 package")
+	}
+	return spec.ToPackage(pkgSpec)
+}
+
+func TestWebGLCall(t *testing.T) {
+	pkg := newWebGLTestPackage(t)
+
+	tt := []struct {
+		name     string
+		expected *WebGLCall
+	}{
+		{"glActiveTexture", &WebGLCall{Kind: "call", Method: "activeTexture", Args: []string{"texture"}}},
+		{"glBindTexture", &WebGLCall{Kind: "call", Method: "bindTexture", Args: []string{"target", "object(texture)"}}},
+		{"glClearDepthf", &WebGLCall{Kind: "call", Method: "clearDepth", Args: []string{"d"}}},
+		{"glBufferData", &WebGLCall{Kind: "call", Method: "bufferData", Args: []string{"target", "bufferSource(data, size)", "usage"}}},
+		{"glBufferSubData", &WebGLCall{Kind: "call", Method: "bufferSubData", Args: []string{"target", "offset", "jsBytes(data, size)"}}},
+		{"glCompressedTexImage2D", &WebGLCall{Kind: "call", Method: "compressedTexImage2D", Args: []string{"target", "level", "internalformat", "width", "height", "border", "jsBytes(data, int(imageSize))"}}},
+		{"glTexImage2D", &WebGLCall{Kind: "call", Method: "texImage2D", Args: []string{"target", "level", "internalformat", "width", "height", "border", "format", "xtype", "pixelData(pixels, format, xtype, width, height, 1)"}}},
+		{"glTexSubImage3D", &WebGLCall{Kind: "call", Method: "texSubImage3D", Args: []string{"target", "level", "xoffset", "yoffset", "zoffset", "width", "height", "depth", "format", "xtype", "pixelData(pixels, format, xtype, width, height, depth)"}}},
+		{"glDrawElements", &WebGLCall{Kind: "call", Method: "drawElements", Args: []string{"mode", "count", "xtype", "int(uintptr(indices))"}}},
+		{"glDrawBuffers", &WebGLCall{Kind: "call", Method: "drawBuffers", Args: []string{"jsUint32s(bufs, int(n))"}}},
+		{"glClearBufferuiv", &WebGLCall{Kind: "call", Method: "clearBufferuiv", Args: []string{"buffer", "drawbuffer", "jsUint32s(value, clearBufferLen(buffer))"}}},
+		{"glUniform1iv", &WebGLCall{Kind: "call", Method: "uniform1iv", Args: []string{"uniformLocation(location)", "jsInt32s(value, int(count))"}}},
+		{"glUniform3fv", &WebGLCall{Kind: "call", Method: "uniform3fv", Args: []string{"uniformLocation(location)", "jsFloat32s(value, int(count)*3)"}}},
+		{"glUniformMatrix4fv", &WebGLCall{Kind: "call", Method: "uniformMatrix4fv", Args: []string{"uniformLocation(location)", "transpose", "jsFloat32s(value, int(count)*16)"}}},
+		{"glUniformMatrix2x3fv", &WebGLCall{Kind: "call", Method: "uniformMatrix2x3fv", Args: []string{"uniformLocation(location)", "transpose", "jsFloat32s(value, int(count)*6)"}}},
+		{"glVertexAttrib3fv", &WebGLCall{Kind: "call", Method: "vertexAttrib3fv", Args: []string{"index", "jsFloat32s(v, 3)"}}},
+		{"glVertexAttribI4uiv", &WebGLCall{Kind: "call", Method: "vertexAttribI4uiv", Args: []string{"index", "jsUint32s(v, 4)"}}},
+		{"glBindAttribLocation", &WebGLCall{Kind: "call", Method: "bindAttribLocation", Args: []string{"object(program)", "index", "GoStr(name)"}}},
+		{"glTransformFeedbackVaryings", &WebGLCall{Kind: "call", Method: "transformFeedbackVaryings", Args: []string{"object(program)", "jsStrs(varyings, int(count))", "bufferMode"}}},
+		{"glTexParameterfv", &WebGLCall{Kind: "call", Method: "texParameterf", Args: []string{"target", "pname", "*params"}}},
+		{"glWaitSync", &WebGLCall{Kind: "call", Method: "waitSync", Args: []string{"object(uint32(sync))", "flags", "int64(timeout)"}}},
+
+		{"glCreateProgram", &WebGLCall{Kind: "call", Method: "createProgram", Result: "newObject(ret)", Returns: true}},
+		{"glFenceSync", &WebGLCall{Kind: "call", Method: "fenceSync", Args: []string{"condition", "flags"}, Result: "uintptr(newObject(ret))", Returns: true}},
+		{"glIsBuffer", &WebGLCall{Kind: "call", Method: "isBuffer", Args: []string{"object(buffer)"}, Result: "ret.Bool()", Returns: true}},
+		{"glGetAttribLocation", &WebGLCall{Kind: "call", Method: "getAttribLocation", Args: []string{"object(program)", "GoStr(name)"}, Result: "int32(ret.Int())", Returns: true}},
+		{"glCheckFramebufferStatus", &WebGLCall{Kind: "call", Method: "checkFramebufferStatus", Args: []string{"target"}, Result: "uint32(ret.Int())", Returns: true}},
+
+		{"glGenBuffers", &WebGLCall{Kind: "helper", Method: "genObjects", Args: []string{`"createBuffer"`, "n", "buffers"}}},
+		{"glGenQueries", &WebGLCall{Kind: "helper", Method: "genObjects", Args: []string{`"createQuery"`, "n", "ids"}}},
+		{"glDeleteVertexArrays", &WebGLCall{Kind: "helper", Method: "deleteObjects", Args: []string{`"deleteVertexArray"`, "n", "arrays"}}},
+		{"glDeleteShader", &WebGLCall{Kind: "helper", Method: "deleteObject", Args: []string{`"deleteShader"`, "shader"}}},
+		{"glDeleteSync", &WebGLCall{Kind: "helper", Method: "deleteObject", Args: []string{`"deleteSync"`, "uint32(sync)"}}},
+		{"glGetUniformLocation", &WebGLCall{Kind: "helper", Method: "getUniformLocation", Args: []string{"program", "GoStr(name)"}, Returns: true}},
+		{"glGetString", &WebGLCall{Kind: "helper", Method: "getString", Args: []string{"name"}, Returns: true}},
+
+		{"glGetIntegerv", &WebGLCall{Kind: "store", Method: "getParameter", Args: []string{"pname"}, Result: "storeInt32s(data, -1, ret)"}},
+		{"glGetBooleanv", &WebGLCall{Kind: "store", Method: "getParameter", Args: []string{"pname"}, Result: "storeBools(data, -1, ret)"}},
+		{"glGetInteger64i_v", &WebGLCall{Kind: "store", Method: "getIndexedParameter", Args: []string{"target", "index"}, Result: "storeInt64s(data, -1, ret)"}},
+		{"glGetUniformuiv", &WebGLCall{Kind: "store", Method: "getUniform", Args: []string{"object(program)", "uniformLocation(location)"}, Result: "storeUint32s(params, -1, ret)"}},
+		{"glGetQueryObjectuiv", &WebGLCall{Kind: "store", Method: "getQueryParameter", Args: []string{"object(id)", "pname"}, Result: "storeUint32s(params, -1, ret)"}},
+		{"glGetActiveUniformsiv", &WebGLCall{Kind: "store", Method: "getActiveUniforms", Args: []string{"object(program)", "jsUint32s(uniformIndices, int(uniformCount))", "pname"}, Result: "storeInt32s(params, -1, ret)"}},
+		{"glGetUniformIndices", &WebGLCall{Kind: "store", Method: "getUniformIndices", Args: []string{"object(program)", "jsStrs(uniformNames, int(uniformCount))"}, Result: "storeUint32s(uniformIndices, -1, ret)"}},
+		{"glGetSynciv", &WebGLCall{Kind: "store", Method: "getSyncParameter", Args: []string{"object(uint32(sync))", "pname"}, Result: "storeCount(length, storeInt32s(values, int(count), ret))"}},
+		{"glGetProgramInfoLog", &WebGLCall{Kind: "store", Method: "getProgramInfoLog", Args: []string{"object(program)"}, Result: "storeString(ret, bufSize, length, infoLog)"}},
+		{"glGetActiveAttrib", &WebGLCall{Kind: "store", Method: "getActiveAttrib", Args: []string{"object(program)", "index"}, Result: "storeActiveInfo(ret, bufSize, length, size, xtype, name)"}},
+
+		{"glMapBufferRange", nil},
+		{"glShaderBinary", nil},
+	}
+
+	for _, tc := range tt {
+		fn, ok := pkg.Functions[tc.name]
+		if !ok {
+			t.Errorf("%s not in package", tc.name)
+			continue
+		}
+		if result := fn.WebGL(); !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("%s: WebGL() = %+v, expected %+v", tc.name, result, tc.expected)
+		}
+	}
+}
+
+func TestWebGLOverload(t *testing.T) {
+	fn := &PackageFunction{Function: Function{
+		Name:   "glGetVertexAttribPointerv",
+		GoName: "GetVertexAttribPointerv",
+	}}
+	o := Overload{
+		GoName:       "GetVertexAttribPointerv",
+		OverloadName: "GetVertexAttribPointerWithOffsetv",
+		Parameters: []Parameter{
+			{Name: "index", Type: Type{Name: "GLuint", CDefinition: "GLuint "}},
+			{Name: "pname", Type: Type{Name: "GLenum", CDefinition: "GLenum "}},
+			{Name: "offset", Type: Type{Name: "uintptr_t", PointerLevel: 2, CDefinition: "uintptr_t **"}},
+		},
+		Return: Type{Name: "void"},
+	}
+	expected := &WebGLCall{Kind: "store", Method: "getVertexAttribOffset", Args: []string{"index", "pname"}, Result: "storeOffset(unsafe.Pointer(offset), ret)"}
+	if result := fn.WebGLOverload(o); !reflect.DeepEqual(result, expected) {
+		t.Errorf("WebGLOverload() = %+v, expected %+v", result, expected)
+	}
+}
+
+// Every OpenGL ES 3.0 function either has a WebGL counterpart or is listed as
+// unsupported, so that new functions are not dropped silently.
+func TestWebGLCoverage(t *testing.T) {
+	pkg := newWebGLTestPackage(t)
+	for _, fn := range pkg.Functions {
+		if (fn.WebGL() == nil) != webglUnsupportedFunctions[fn.Name] {
+			t.Errorf("%s: WebGL() = %+v, unsupported = %v", fn.Name, fn.WebGL(), webglUnsupportedFunctions[fn.Name])
+		}
+	}
+	for name := range webglUnsupportedFunctions {
+		if _, ok := pkg.Functions[name]; !ok {
+			t.Errorf("unsupported function %s is not an OpenGL ES 3.0 function", name)
+		}
+	}
+	for name := range webglUnsupportedEnums {
+		if _, ok := pkg.Enums[name]; ok {
+			t.Errorf("package has unsupported enum %s", name)
+		}
+	}
+	if len(pkg.Extensions) != 0 {
+		t.Errorf("package has extensions %v", pkg.Extensions)
+	}
+}
+
+func TestGenerateWebGLPackage(t *testing.T) {
+	pkg := newWebGLTestPackage(t)
+	files := generatePackage(t, pkg)
+
+	var names []string
+	for name, src := range files {
+		names = append(names, name)
+		if !regexp.MustCompile(`(?m)^//go:build js && wasm`).Match(src) {
+			t.Errorf("%s is not restricted to js/wasm", name)
+		}
+		if bytes.Contains(src, []byte(`import "C"`)) {
+			t.Errorf("%s uses cgo", name)
+		}
+	}
+	if len(files) != 4 || files["package.go"] == nil || files["conversions.go"] == nil || files["enumnames.go"] == nil || files["errors.go"] == nil {
+		t.Errorf("unexpected files %v", names)
+	}
+
+	src := files["package.go"]
+	for _, expected := range []*regexp.Regexp{
+		regexp.MustCompile(`func Init\(context js.Value\) error`),
+		regexp.MustCompile(`"glDrawArrays": +true,`),
+		regexp.MustCompile(`"glMapBufferRange": +false,`),
+		regexp.MustCompile(`// MapBufferRange has no WebGL counterpart: calling it panics.\nfunc MapBufferRange\(`),
+		regexp.MustCompile(`panic\("gles2: glMapBufferRange is not supported by WebGL"\)`),
+		regexp.MustCompile(`gl.Call\("bindBuffer", target, object\(buffer\)\)`),
+		regexp.MustCompile(`ret := gl.Call\("getParameter", pname\)\n\s+storeInt32s\(data, -1, ret\)`),
+		regexp.MustCompile(`ret := gl.Call\("isProgram", object\(program\)\)\n\s+return ret.Bool\(\)`),
+		regexp.MustCompile(`genObjects\("createTexture", n, textures\)`),
+		regexp.MustCompile(`func GetError\(\) Error {\n\s+ret := gl.Call\("getError"\)\n\s+return Error\(ret.Int\(\)\)`),
+		regexp.MustCompile(`TEXTURE_2D += 0x0DE1`),
+	} {
+		if !expected.Match(src) {
+			t.Errorf("package.go does not match %s", expected)
+		}
+	}
+	for _, unexpected := range []string{"MAP_READ_BIT", "PROGRAM_BINARY_FORMATS", "COMPRESSED_RGB8_ETC2", "procAddr"} {
+		if bytes.Contains(src, []byte(unexpected)) {
+			t.Errorf("package.go contains %s", unexpected)
+		}
+	}
+}
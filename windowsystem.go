@@ -0,0 +1,410 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// A windowSystemType describes how a type of a window system API, i.e., EGL,
+// GLX, or WGL, maps to C and Go. The registries of these APIs leave many types
+// to platform headers, e.g., EGLint to EGL/eglplatform.h and HDC to windows.h.
+// Packages declare these types themselves so that they build without the
+// headers, and define handles as pointer-width integers, like GLsync, because
+// implementations may store non-pointer values in them.
+type windowSystemType struct {
+	cDef    string // C definition replacing that of the registry, if any
+	goType  string // Go type of the C type, if not mapped like other APIs' types
+	goBase  string // Underlying type of goType if the package declares it
+	boolean bool   // Whether values of the (integer) type map to Go bool
+	header  string // Header declaring the type, if the package cannot declare it
+}
+
+// windowSystemTypes maps the window system APIs to their types by C name.
+var windowSystemTypes = map[string]map[string]windowSystemType{
+	"egl": {
+		"eglplatform": {cDef: eglPlatformTypedefs},
+
+		"char": {goType: "uint8"},
+		"int":  {goType: "int32"},
+
+		"EGLint":                     {goType: "int32"},
+		"EGLBoolean":                 {goType: "uint32", boolean: true},
+		"EGLenum":                    {goType: "uint32"},
+		"EGLAttrib":                  {goType: "int"},
+		"EGLAttribKHR":               {goType: "int"},
+		"EGLTime":                    {goType: "uint64"},
+		"EGLTimeKHR":                 {goType: "uint64"},
+		"EGLTimeNV":                  {goType: "uint64"},
+		"EGLuint64NV":                {goType: "uint64"},
+		"EGLuint64KHR":               {goType: "uint64"},
+		"EGLnsecsANDROID":            {goType: "int64"},
+		"EGLNativeFileDescriptorKHR": {goType: "int32"},
+		"EGLsizeiANDROID":            {goType: "int"},
+
+		"EGLNativeDisplayType": {goType: "NativeDisplayType", goBase: "uintptr"},
+		"EGLNativePixmapType":  {goType: "NativePixmapType", goBase: "uintptr"},
+		"EGLNativeWindowType":  {goType: "NativeWindowType", goBase: "uintptr"},
+
+		"EGLClientBuffer":   handle("EGLClientBuffer", "ClientBuffer"),
+		"EGLConfig":         handle("EGLConfig", "Config"),
+		"EGLContext":        handle("EGLContext", "Context"),
+		"EGLDeviceEXT":      handle("EGLDeviceEXT", "DeviceEXT"),
+		"EGLDisplay":        handle("EGLDisplay", "Display"),
+		"EGLImage":          handle("EGLImage", "Image"),
+		"EGLImageKHR":       handle("EGLImageKHR", "ImageKHR"),
+		"EGLLabelKHR":       handle("EGLLabelKHR", "LabelKHR"),
+		"EGLObjectKHR":      handle("EGLObjectKHR", "ObjectKHR"),
+		"EGLOutputLayerEXT": handle("EGLOutputLayerEXT", "OutputLayerEXT"),
+		"EGLOutputPortEXT":  handle("EGLOutputPortEXT", "OutputPortEXT"),
+		"EGLStreamKHR":      handle("EGLStreamKHR", "StreamKHR"),
+		"EGLSurface":        handle("EGLSurface", "Surface"),
+		"EGLSync":           handle("EGLSync", "Sync"),
+		"EGLSyncKHR":        handle("EGLSyncKHR", "SyncKHR"),
+		"EGLSyncNV":         handle("EGLSyncNV", "SyncNV"),
+
+		"__eglMustCastToProperFunctionPointerType": {goType: "unsafe.Pointer"},
+	},
+	"glx": {
+		"char":         {goType: "uint8"},
+		"int":          {goType: "int32"},
+		"unsignedint":  {goType: "uint32"},
+		"unsignedlong": {goType: "uint"},
+
+		"GLbitfield": {cDef: "typedef unsigned int GLbitfield;"},
+		"GLboolean":  {cDef: "typedef unsigned char GLboolean;", goType: "uint8", boolean: true},
+		"GLenum":     {cDef: "typedef unsigned int GLenum;"},
+		"GLfloat":    {cDef: "typedef float GLfloat;"},
+		"GLint":      {cDef: "typedef int GLint;"},
+		"GLintptr":   {cDef: "typedef intptr_t GLintptr;"},
+		"GLsizei":    {cDef: "typedef int GLsizei;"},
+		"GLsizeiptr": {cDef: "typedef intptr_t GLsizeiptr;"},
+		"GLubyte":    {cDef: "typedef unsigned char GLubyte;"},
+		"GLuint":     {cDef: "typedef unsigned int GLuint;"},
+
+		"Bool":        {cDef: "typedef int Bool;", goType: "int32", boolean: true},
+		"Status":      {cDef: "typedef int Status;", goType: "int32"},
+		"Display":     {cDef: "typedef struct _XDisplay Display;", goType: "XDisplay"},
+		"XVisualInfo": {cDef: xVisualInfoTypedef, goType: "XVisualInfo"},
+		"Colormap":    {cDef: "typedef unsigned long Colormap;", goType: "XColormap", goBase: "uint"},
+		"Font":        {cDef: "typedef unsigned long Font;", goType: "XFont", goBase: "uint"},
+		"Pixmap":      {cDef: "typedef unsigned long Pixmap;", goType: "XPixmap", goBase: "uint"},
+		"Window":      {cDef: "typedef unsigned long Window;", goType: "XWindow", goBase: "uint"},
+
+		"GLXContextID":            {cDef: "typedef unsigned long GLXContextID;", goType: "ContextID", goBase: "uint"},
+		"GLXDrawable":             {cDef: "typedef unsigned long GLXDrawable;", goType: "Drawable", goBase: "uint"},
+		"GLXFBConfigID":           {cDef: "typedef unsigned long GLXFBConfigID;", goType: "FBConfigID", goBase: "uint"},
+		"GLXFBConfigIDSGIX":       {cDef: "typedef unsigned long GLXFBConfigIDSGIX;", goType: "FBConfigIDSGIX", goBase: "uint"},
+		"GLXPbuffer":              {cDef: "typedef unsigned long GLXPbuffer;", goType: "Pbuffer", goBase: "uint"},
+		"GLXPbufferSGIX":          {cDef: "typedef unsigned long GLXPbufferSGIX;", goType: "PbufferSGIX", goBase: "uint"},
+		"GLXPixmap":               {cDef: "typedef unsigned long GLXPixmap;", goType: "Pixmap", goBase: "uint"},
+		"GLXVideoCaptureDeviceNV": {cDef: "typedef unsigned long GLXVideoCaptureDeviceNV;", goType: "VideoCaptureDeviceNV", goBase: "uint"},
+		"GLXVideoSourceSGIX":      {cDef: "typedef unsigned long GLXVideoSourceSGIX;", goType: "VideoSourceSGIX", goBase: "uint"},
+		"GLXWindow":               {cDef: "typedef unsigned long GLXWindow;", goType: "Window", goBase: "uint"},
+		"GLXVideoDeviceNV":        {goType: "VideoDeviceNV", goBase: "uint32"},
+		"GLXContext":              handle("GLXContext", "Context"),
+		"GLXFBConfig":             handle("GLXFBConfig", "FBConfig"),
+		"GLXFBConfigSGIX":         handle("GLXFBConfigSGIX", "FBConfigSGIX"),
+
+		"__GLXextFuncPtr": {goType: "unsafe.Pointer"},
+
+		// Digital media and video library types of SGI extensions
+		"DMbuffer": {header: "dmedia/dm_buffer.h"},
+		"DMparams": {header: "dmedia/dm_params.h"},
+		"VLNode":   {header: "vl/vl.h"},
+		"VLPath":   {header: "vl/vl.h"},
+		"VLServer": {header: "vl/vl.h"},
+	},
+	"wgl": {
+		"char":         {goType: "uint8"},
+		"int":          {goType: "int32"},
+		"unsignedint":  {goType: "uint32"},
+		"unsignedlong": {goType: "uint32"},
+
+		"GLbitfield": {cDef: "typedef unsigned int GLbitfield;"},
+		"GLboolean":  {cDef: "typedef unsigned char GLboolean;", goType: "uint8", boolean: true},
+		"GLenum":     {cDef: "typedef unsigned int GLenum;"},
+		"GLfloat":    {cDef: "typedef float GLfloat;"},
+		"GLint":      {cDef: "typedef int GLint;"},
+		"GLsizei":    {cDef: "typedef int GLsizei;"},
+		"GLuint":     {cDef: "typedef unsigned int GLuint;"},
+		"GLushort":   {cDef: "typedef unsigned short GLushort;"},
+
+		"BOOL":     {cDef: "typedef int BOOL;", goType: "int32", boolean: true},
+		"CHAR":     {cDef: "typedef char CHAR;", goType: "uint8"},
+		"COLORREF": {cDef: "typedef uint32_t COLORREF;", goType: "uint32"},
+		"DWORD":    {cDef: "typedef uint32_t DWORD;", goType: "uint32"},
+		"FLOAT":    {cDef: "typedef float FLOAT;", goType: "float32"},
+		"INT":      {cDef: "typedef int INT;", goType: "int32"},
+		"INT32":    {cDef: "typedef int32_t INT32;", goType: "int32"},
+		"INT64":    {cDef: "typedef int64_t INT64;", goType: "int64"},
+		"UINT":     {cDef: "typedef unsigned int UINT;", goType: "uint32"},
+		"USHORT":   {cDef: "typedef unsigned short USHORT;", goType: "uint16"},
+		"VOID":     {cDef: "typedef void VOID;"},
+		"LPCSTR":   {cDef: "typedef const char *LPCSTR;", goType: "*uint8"},
+		"LPVOID":   {cDef: "typedef void *LPVOID;", goType: "unsafe.Pointer"},
+		"PROC":     {cDef: "typedef void *PROC;", goType: "unsafe.Pointer"},
+		"RECT":     {cDef: "typedef struct tagRECT {\n    int32_t left;\n    int32_t top;\n    int32_t right;\n    int32_t bottom;\n} RECT;"},
+
+		"LAYERPLANEDESCRIPTOR":  {cDef: "typedef struct tagLAYERPLANEDESCRIPTOR LAYERPLANEDESCRIPTOR;"},
+		"LPGLYPHMETRICSFLOAT":   {cDef: "typedef struct _GLYPHMETRICSFLOAT *LPGLYPHMETRICSFLOAT;"},
+		"PIXELFORMATDESCRIPTOR": {cDef: pixelFormatDescriptorTypedef, goType: "PixelFormatDescriptor"},
+
+		"HANDLE":       handle("HANDLE", "HANDLE"),
+		"HDC":          handle("HDC", "HDC"),
+		"HENHMETAFILE": handle("HENHMETAFILE", "HENHMETAFILE"),
+		"HGLRC":        handle("HGLRC", "HGLRC"),
+
+		"HGPUNV":               handle("HGPUNV", "HGPUNV"),
+		"HPBUFFERARB":          handle("HPBUFFERARB", "HPBUFFERARB"),
+		"HPBUFFEREXT":          handle("HPBUFFEREXT", "HPBUFFEREXT"),
+		"HPGPUNV":              handle("HPGPUNV", "HPGPUNV"),
+		"HPVIDEODEV":           handle("HPVIDEODEV", "HPVIDEODEV"),
+		"HVIDEOINPUTDEVICENV":  handle("HVIDEOINPUTDEVICENV", "HVIDEOINPUTDEVICENV"),
+		"HVIDEOOUTPUTDEVICENV": handle("HVIDEOOUTPUTDEVICENV", "HVIDEOOUTPUTDEVICENV"),
+	},
+}
+
+// handle describes a handle type, e.g., EGLDisplay, which the package defines
+// as a pointer-width integer.
+func handle(name, goType string) windowSystemType {
+	return windowSystemType{cDef: "typedef uintptr_t " + name + ";", goType: goType, goBase: "uintptr"}
+}
+
+// eglPlatformTypedefs replaces EGL/eglplatform.h. Native types are those of
+// EGL_NO_PLATFORM_SPECIFIC_TYPES, but pointer-width integers like handles.
+const eglPlatformTypedefs = `typedef khronos_int32_t EGLint;
+typedef uintptr_t EGLNativeDisplayType;
+typedef uintptr_t EGLNativePixmapType;
+typedef uintptr_t EGLNativeWindowType;`
+
+// xVisualInfoTypedef declares XVisualInfo as X11/Xutil.h does.
+const xVisualInfoTypedef = `typedef struct {
+    void *visual;
+    unsigned long visualid;
+    int screen;
+    int depth;
+    int c_class;
+    unsigned long red_mask;
+    unsigned long green_mask;
+    unsigned long blue_mask;
+    int colormap_size;
+    int bits_per_rgb;
+} XVisualInfo;`
+
+// pixelFormatDescriptorTypedef declares PIXELFORMATDESCRIPTOR as wingdi.h
+// does.
+const pixelFormatDescriptorTypedef = `typedef struct tagPIXELFORMATDESCRIPTOR {
+    uint16_t nSize;
+    uint16_t nVersion;
+    uint32_t dwFlags;
+    uint8_t iPixelType;
+    uint8_t cColorBits;
+    uint8_t cRedBits;
+    uint8_t cRedShift;
+    uint8_t cGreenBits;
+    uint8_t cGreenShift;
+    uint8_t cBlueBits;
+    uint8_t cBlueShift;
+    uint8_t cAlphaBits;
+    uint8_t cAlphaShift;
+    uint8_t cAccumBits;
+    uint8_t cAccumRedBits;
+    uint8_t cAccumGreenBits;
+    uint8_t cAccumBlueBits;
+    uint8_t cAccumAlphaBits;
+    uint8_t cDepthBits;
+    uint8_t cStencilBits;
+    uint8_t cAuxBuffers;
+    uint8_t iLayerType;
+    uint8_t bReserved;
+    uint32_t dwLayerMask;
+    uint32_t dwVisibleMask;
+    uint32_t dwDamageMask;
+} PIXELFORMATDESCRIPTOR;`
+
+// A WindowSystemType describes a Go type declared by a window system package
+// for a C type, e.g., Display for EGLDisplay.
+type WindowSystemType struct {
+	Name   string // C name of the type
+	GoName string // Name of the Go type
+	GoBase string // Underlying Go type
+}
+
+// windowSystemType returns how the type maps to C and Go, if it is a type of
+// a window system API.
+func (t Type) windowSystemType() (windowSystemType, bool) {
+	ws, ok := windowSystemTypes[t.WindowSystem][t.Name]
+	return ws, ok
+}
+
+// windowSystemAPI returns the window system API described by the
+// specification, i.e., "egl", "glx", or "wgl", or "" if it describes another
+// API.
+func (spec *Specification) windowSystemAPI() string {
+	for _, feature := range spec.Features {
+		if _, ok := windowSystemTypes[feature.API]; ok {
+			return feature.API
+		}
+	}
+	return ""
+}
+
+// declareWindowSystemTypes replaces the registry definitions of the types of
+// a window system API by those of windowSystemTypes. Types the package cannot
+// declare are included from their header, which extensions referring to them
+// then require.
+func (spec *Specification) declareWindowSystemTypes() {
+	types := windowSystemTypes[spec.windowSystemAPI()]
+	for _, typedef := range spec.Typedefs {
+		ws, ok := types[typedef.typedef.Name]
+		switch {
+		case !ok:
+			// Definitions such as that of _GPU_DEVICE name the types they use in
+			// plain text rather than in type tags
+			for _, word := range identifierRegexp.FindAllString(typedef.typedef.CDefinition, -1) {
+				if _, ok := types[word]; ok {
+					typedef.requires = append(typedef.requires, word)
+				}
+			}
+		case ws.header != "":
+			typedef.typedef.CDefinition = "#include <" + ws.header + ">"
+			typedef.typedef.Category = "include"
+		case ws.cDef != "":
+			typedef.typedef.CDefinition = ws.cDef
+		}
+	}
+}
+
+var (
+	windowSystemCastRegexp = regexp.MustCompile(`^EGL_CAST\((\w+),\s*(-?\w+)\)$`)
+	conversionRegexp       = regexp.MustCompile(`^(\w+)\(`)
+	identifierRegexp       = regexp.MustCompile(`\b[A-Za-z_]\w*\b`)
+)
+
+// windowSystemEnumValue converts an enum value that casts to a type of a
+// window system API, e.g., "EGL_CAST(EGLDisplay,0)", to a Go conversion, e.g.,
+// "Display(0)".
+func windowSystemEnumValue(value string) string {
+	match := windowSystemCastRegexp.FindStringSubmatch(value)
+	if match == nil {
+		return value
+	}
+	goType := windowSystemTypes["egl"][match[1]].goType
+	if goType == "" {
+		return match[2]
+	}
+	return goType + "(" + match[2] + ")"
+}
+
+// IsEGL returns whether the package targets EGL.
+func (pkg *Package) IsEGL() bool {
+	return pkg.API == "egl"
+}
+
+// IsGLX returns whether the package targets GLX.
+func (pkg *Package) IsGLX() bool {
+	return pkg.API == "glx"
+}
+
+// IsWGL returns whether the package targets WGL.
+func (pkg *Package) IsWGL() bool {
+	return pkg.API == "wgl"
+}
+
+// IsWindowSystem returns whether the package targets the window system API
+// EGL, GLX, or WGL rather than a rendering API.
+func (pkg *Package) IsWindowSystem() bool {
+	_, ok := windowSystemTypes[pkg.API]
+	return ok
+}
+
+// headlessFunctions lists the functions NewHeadlessContext calls.
+var headlessFunctions = []string{
+	"eglGetError",
+	"eglGetPlatformDisplay",
+	"eglInitialize",
+	"eglTerminate",
+	"eglQueryString",
+	"eglBindAPI",
+	"eglChooseConfig",
+	"eglCreateContext",
+	"eglDestroyContext",
+	"eglCreatePbufferSurface",
+	"eglDestroySurface",
+	"eglMakeCurrent",
+}
+
+// HasHeadlessContext returns whether the package includes NewHeadlessContext,
+// which requires the headless option and the EGL 1.5 functions it calls.
+func (pkg *Package) HasHeadlessContext() bool {
+	if !pkg.Headless || !pkg.IsEGL() || !pkg.HasErrorQuery() {
+		return false
+	}
+	for _, name := range headlessFunctions {
+		if !pkg.HasFunction(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// WindowSystemTypes returns the Go types the package declares for the C types
+// of its window system API, ordered by Go name.
+func (pkg *Package) WindowSystemTypes() []*WindowSystemType {
+	types := windowSystemTypes[pkg.API]
+	declared := make(map[string]*WindowSystemType)
+	declare := func(name string) {
+		if ws, ok := types[name]; ok && ws.goBase != "" {
+			declared[ws.goType] = &WindowSystemType{Name: name, GoName: ws.goType, GoBase: ws.goBase}
+		}
+	}
+	for _, typedef := range pkg.Typedefs {
+		declare(typedef.Name)
+	}
+	// Enums such as EGL_NO_DISPLAY convert to the type they cast to
+	for _, enum := range pkg.Enums {
+		if match := conversionRegexp.FindStringSubmatch(enum.Value); match != nil {
+			for name, ws := range types {
+				if ws.goType == match[1] {
+					declare(name)
+				}
+			}
+		}
+	}
+	sorted := make([]*WindowSystemType, 0, len(declared))
+	for _, t := range declared {
+		sorted = append(sorted, t)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].GoName < sorted[j].GoName })
+	return sorted
+}
+
+// HasWindowSystemType returns whether the package declares the named C type of
+// its window system API, e.g., "XVisualInfo".
+func (pkg *Package) HasWindowSystemType(name string) bool {
+	for _, typedef := range pkg.Typedefs {
+		if typedef.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isWindowSystemPointer returns whether values of the type convert between C
+// and Go through unsafe.Pointer, e.g., EGLConfig* to *Config or LPCSTR to
+// *uint8.
+func (t Type) isWindowSystemPointer() bool {
+	ws, ok := t.windowSystemType()
+	if !ok || ws.goType == "" || ws.goType == "unsafe.Pointer" {
+		return false
+	}
+	return t.PointerLevel >= 1 || strings.HasPrefix(ws.goType, "*")
+}
+
+// isWindowSystemBoolean returns whether values of the type map to Go bool,
+// e.g., those of EGLBoolean.
+func (t Type) isWindowSystemBoolean() bool {
+	ws, ok := t.windowSystemType()
+	return ok && ws.boolean && t.PointerLevel == 0
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func newWindowSystemTestPackage(t *testing.T, api string, version Version) *Package {
+	t.Helper()
+	registry, err := readSpecFile(filepath.Join("xml", "spec", api+".xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := NewSpecification(*registry, xmlOverloads{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgSpec := &PackageSpec{API: api, Version: version, TmplDir: "tmpl"}
+	if !spec.HasPackage(pkgSpec) {
+		t.Fatalf("registry cannot generate %s package", api)
+	}
+	return spec.ToPackage(pkgSpec)
+}
+
+func TestWindowSystemGoType(t *testing.T) {
+	tt := []struct {
+		in       Type
+		expected string
+		goToC    string
+		cToGo    string
+	}{
+		{Type{Name: "EGLDisplay", WindowSystem: "egl"}, "Display", "(C.EGLDisplay)(x)", "(Display)(x)"},
+		{Type{Name: "EGLConfig", PointerLevel: 1, WindowSystem: "egl"}, "*Config", "(*C.EGLConfig)(unsafe.Pointer(x))", "(*Config)(unsafe.Pointer(x))"},
+		{Type{Name: "EGLBoolean", WindowSystem: "egl"}, "bool", "(C.EGLBoolean)(boolToInt(x))", "x != 0"},
+		{Type{Name: "EGLint", PointerLevel: 1, WindowSystem: "egl"}, "*int32", "(*C.EGLint)(unsafe.Pointer(x))", "(*int32)(unsafe.Pointer(x))"},
+		{Type{Name: "EGLAttrib", WindowSystem: "egl"}, "int", "(C.EGLAttrib)(x)", "(int)(x)"},
+		{Type{Name: "Display", PointerLevel: 1, WindowSystem: "glx"}, "*XDisplay", "(*C.Display)(unsafe.Pointer(x))", "(*XDisplay)(unsafe.Pointer(x))"},
+		{Type{Name: "Bool", WindowSystem: "glx"}, "bool", "(C.Bool)(boolToInt(x))", "x != 0"},
+		{Type{Name: "GLXDrawable", WindowSystem: "glx"}, "Drawable", "(C.GLXDrawable)(x)", "(Drawable)(x)"},
+		{Type{Name: "unsignedlong", WindowSystem: "glx"}, "uint", "(C.ulong)(x)", "(uint)(x)"},
+		{Type{Name: "HDC", WindowSystem: "wgl"}, "HDC", "(C.HDC)(x)", "(HDC)(x)"},
+		{Type{Name: "LPCSTR", WindowSystem: "wgl"}, "*uint8", "(C.LPCSTR)(unsafe.Pointer(x))", "(*uint8)(unsafe.Pointer(x))"},
+		{Type{Name: "PROC", WindowSystem: "wgl"}, "unsafe.Pointer", "(C.PROC)(x)", "(unsafe.Pointer)(x)"},
+		{Type{Name: "unsignedlong", WindowSystem: "wgl"}, "uint32", "(C.ulong)(x)", "(uint32)(x)"},
+		// Other APIs' types of the same name are unaffected
+		{Type{Name: "Display", PointerLevel: 1}, "unsafe.Pointer", "(*C.Display)(x)", "(unsafe.Pointer)(x)"},
+	}
+	for _, tc := range tt {
+		if goType := tc.in.GoType(); goType != tc.expected {
+			t.Errorf("GoType(%v) = %q, expected %q", tc.in, goType, tc.expected)
+		}
+		if goToC := tc.in.ConvertGoToC("x"); goToC != tc.goToC {
+			t.Errorf("ConvertGoToC(%v) = %q, expected %q", tc.in, goToC, tc.goToC)
+		}
+		if cToGo := tc.in.ConvertCToGo("x"); cToGo != tc.cToGo {
+			t.Errorf("ConvertCToGo(%v) = %q, expected %q", tc.in, cToGo, tc.cToGo)
+		}
+	}
+}
+
+func TestGenerateWindowSystemPackages(t *testing.T) {
+	tt := []struct {
+		api      string
+		version  Version
+		expected map[string][]string
+		excluded map[string][]string
+	}{
+		{"egl", Version{1, 5}, map[string][]string{
+			"package.go": {
+				"// Package egl implements Go bindings to EGL.",
+				"// typedef uintptr_t EGLDisplay;",
+				"func GetDisplay(display_id NativeDisplayType) Display {",
+				"func ChooseConfig(dpy Display, attrib_list *int32, configs *Config, config_size int32, num_config *int32) bool {",
+				"func GetPlatformDisplay(platform uint32, native_display unsafe.Pointer, attrib_list *int) Display {",
+				"(*C.struct_wl_display)(display)",
+				"NO_DISPLAY                                     = Display(0)",
+				"DONT_CARE                                      = int32(-1)",
+			},
+			"procaddr.go": {
+				`#define GLOW_EGL_LIBRARY "libEGL.so.1"`,
+				`GlowGetLibraryProcAddress("eglGetProcAddress")`,
+			},
+			"windowsystem.go": {
+				"type Display uintptr",
+				"type NativeWindowType uintptr",
+				"func Attribs(attribs ...int32) *int32 {",
+				"func IntAttribs(attribs ...int) *int {",
+			},
+		}, map[string][]string{
+			"package.go": {"#cgo", "windows.h"},
+		}},
+		{"glx", Version{1, 4}, map[string][]string{
+			"package.go": {
+				"// Package glx implements Go bindings to GLX.",
+				"func ChooseFBConfig(dpy *XDisplay, screen int32, attrib_list *int32, nelements *int32) *FBConfig {",
+				"func MakeContextCurrent(dpy *XDisplay, draw Drawable, read Drawable, ctx Context) bool {",
+			},
+			"procaddr.go": {
+				`#define GLOW_GLX_LIBRARY "libGL.so.1"`,
+				`GlowGetLibraryProcAddress("glXGetProcAddressARB")`,
+			},
+			"windowsystem.go": {
+				"type FBConfig uintptr",
+				"type Drawable uint",
+				"type XDisplay struct{}",
+				"_ [unsafe.Sizeof(XVisualInfo{}) - unsafe.Sizeof(C.XVisualInfo{})]byte",
+			},
+		}, map[string][]string{
+			"package.go": {"dmedia/", "vl/vl.h"},
+		}},
+		{"wgl", Version{1, 0}, map[string][]string{
+			"package.go": {
+				"// Package wgl implements Go bindings to WGL.",
+				"func MakeCurrent(hDc HDC, newContext HGLRC) bool {",
+				"func ChoosePixelFormat(hDc HDC, pPfd *PixelFormatDescriptor) int32 {",
+				"func CreateContextAttribsARB(hDC HDC, hShareContext HGLRC, attribList *int32) HGLRC {",
+			},
+			"procaddr.go": {
+				`LoadLibraryA("opengl32.dll")`,
+			},
+			"windowsystem.go": {
+				"type HGLRC uintptr",
+				"func FloatAttribs(attribs ...float32) *float32 {",
+			},
+		}, nil},
+	}
+	for _, tc := range tt {
+		pkg := newWindowSystemTestPackage(t, tc.api, tc.version)
+		files := generatePackage(t, pkg)
+		for name, contents := range tc.expected {
+			for _, content := range contents {
+				if !bytes.Contains(files[name], []byte(content)) {
+					t.Errorf("%s/%s does not contain %q", tc.api, name, content)
+				}
+			}
+		}
+		for name, contents := range tc.excluded {
+			for _, content := range contents {
+				if bytes.Contains(files[name], []byte(content)) {
+					t.Errorf("%s/%s contains %q", tc.api, name, content)
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateHeadlessPackage(t *testing.T) {
+	tt := []struct {
+		headless bool
+		version  Version
+		expected bool
+	}{
+		{true, Version{1, 5}, true},
+		{false, Version{1, 5}, false},
+		{true, Version{1, 4}, false},
+	}
+	for _, tc := range tt {
+		pkg := newWindowSystemTestPackage(t, "egl", tc.version)
+		pkg.Headless = tc.headless
+		if pkg.HasHeadlessContext() != tc.expected {
+			t.Errorf("HasHeadlessContext(%v, %v) = %v, expected %v", tc.headless, tc.version, !tc.expected, tc.expected)
+		}
+	}
+
+	pkg := newWindowSystemTestPackage(t, "egl", Version{1, 5})
+	pkg.Headless = true
+	files := generatePackage(t, pkg)
+
+	expected := map[string][]string{
+		"headless.go": {
+			"func NewHeadlessContext(config HeadlessConfig) (*HeadlessContext, error) {",
+			"dpy = GetPlatformDisplay(PLATFORM_DEVICE_EXT, *(*unsafe.Pointer)(unsafe.Pointer(&device)), nil)",
+			"dpy = GetPlatformDisplay(PLATFORM_SURFACELESS_MESA, nil, nil)",
+			"func ProcAddr(name string) unsafe.Pointer {",
+		},
+		"errors.go": {
+			"case ErrBadMatch:",
+			"if code == SUCCESS {",
+			"case SUCCESS:\n\t\treturn \"SUCCESS\"",
+		},
+		"package.go": {
+			"func GetError() Error {",
+		},
+	}
+	for name, contents := range expected {
+		for _, content := range contents {
+			if !bytes.Contains(files[name], []byte(content)) {
+				t.Errorf("%s does not contain %q", name, content)
+			}
+		}
+	}
+}